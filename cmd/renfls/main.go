@@ -4,10 +4,12 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/shoarai/renfls"
@@ -18,22 +20,148 @@ const separator = ","
 // Flag parameter
 var dest string
 var ext string
+var caseSensitive bool
 var reg string
+var glob string
+var where string
+var excludeDir string
+var includes rules
+var excludes rules
+var minSize string
+var maxSize string
+var newer string
+var older string
+var fileTypes string
 var ignore bool
+var dryRun bool
+var journalPath string
+var transactional bool
+var name string
+var onConflict string
+var skipIdentical bool
+var suffixFormat string
+var suffixStart int
+var dedup string
+var reportFormat string
+var continueOnError bool
+var workers int
+var order string
+var pathName string
+var pathSep string
+var minDepth int
+var maxDepth int
+var mode string
+var verify bool
+var symlinks string
+var hidden bool
+var junk string
+var dirs bool
+
+// rules is a flag of rules that can be repeated.
+type rules []renfls.Rule
+
+func (r *rules) String() string {
+	return fmt.Sprint(*r)
+}
+
+func (r *rules) Set(s string) error {
+	rule, e := renfls.ParseRule(s)
+	if e != nil {
+		return e
+	}
+	*r = append(*r, rule)
+	return nil
+}
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitPartial = 1
+	exitFatal   = 2
+)
 
 func main() {
 	flag.StringVar(&dest, "dest", "", "Destination to which renamed files are moved")
 	flag.StringVar(&ext, "ext", "",
 		fmt.Sprintf("Extension list separated by %q", separator))
+	flag.BoolVar(&caseSensitive, "case-sensitive", false, "Match extensions case-sensitively")
 	flag.StringVar(&reg, "reg", "", "Regex")
+	flag.StringVar(&glob, "glob", "",
+		"Rename files only matching glob list separated by \",\" like \"**/raw/*.CR2\"")
+	flag.Var(&includes, "include",
+		"Rename files only matching rule like \"glob:*.jpg\", \"ext:jpg,png\" or \"reg:^IMG\" (repeatable)")
+	flag.Var(&excludes, "exclude", "Don't rename files matching rule (repeatable)")
+	flag.StringVar(&excludeDir, "exclude-dir", strings.Join(renfls.DefaultExcludeDirs, separator),
+		"Directories not walked matching glob list separated by \",\"")
+	flag.StringVar(&where, "where", "",
+		"Rename files only matching filter expression like \"ext in (jpg,png) and size > 100k\"")
+	flag.StringVar(&minSize, "min-size", "", "Rename files only at least the size like 10MB")
+	flag.StringVar(&maxSize, "max-size", "", "Rename files only at most the size like 10MB")
+	flag.StringVar(&newer, "newer", "", "Rename files only modified after the time like 2017-01-01")
+	flag.StringVar(&older, "older", "", "Rename files only modified before the time like 2017-01-01")
+	flag.StringVar(&fileTypes, "type", "",
+		"Rename files only of type list separated by \",\": "+strings.Join(renfls.FileTypeNames(), ", "))
 	flag.BoolVar(&ignore, "ignore", false,
 		"Flag whether files matching pattern are renamed or ignored.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Print operations without renaming files.")
+	flag.StringVar(&journalPath, "journal", "",
+		"Journal file to which operations are recorded to undo them")
+	flag.BoolVar(&transactional, "transaction", false,
+		"Roll back every renamed file if renaming fails")
+	flag.StringVar(&name, "name", "",
+		"Template of new file names like \"{dir}_{mtime:2006-01-02}_{index:03}\"")
+	flag.StringVar(&pathName, "path-name", "",
+		"Name files by all the directories from the sub directory (all) or the nearest number of them")
+	flag.StringVar(&pathSep, "path-sep", "_", "Separator of directories in names with -path-name")
+	flag.StringVar(&onConflict, "on-conflict", "suffix",
+		"Strategy for new names that already exist: suffix, skip, overwrite, fail, keep-newer, keep-larger or rename-existing")
+	flag.BoolVar(&skipIdentical, "skip-identical", false,
+		"Skip files that have the same content as the existing files")
+	flag.StringVar(&suffixFormat, "suffix", "-%d", "Format of suffixes added to new names")
+	flag.IntVar(&suffixStart, "suffix-start", 2, "First number of suffixes")
+	flag.StringVar(&dedup, "dedup", "off",
+		"Action for files that have the same content as the existing files: off, move, link or skip")
+	flag.StringVar(&reportFormat, "report", "", "Print the result of every file as table, json or csv")
+	flag.BoolVar(&continueOnError, "continue", false,
+		"Keep renaming files after a file fails")
+	flag.IntVar(&workers, "j", 1, "Number of workers that read directories concurrently")
+	flag.IntVar(&minDepth, "mindepth", 0, "Rename files only at least the depth in each directory")
+	flag.IntVar(&maxDepth, "maxdepth", 0, "Rename files only at most the depth in each directory")
+	flag.StringVar(&mode, "mode", "move",
+		"Way to put files at new names: move, copy, hardlink, symlink or reflink")
+	flag.BoolVar(&verify, "verify", false,
+		"Compare checksums of files moved across file systems before removing the originals")
+	flag.StringVar(&order, "order", "name",
+		"Order of files in a directory: name, natural, mtime, size or exif")
+	flag.StringVar(&symlinks, "symlinks", "move",
+		"Way to walk symbolic links: move, skip, target or follow")
+	flag.BoolVar(&hidden, "hidden", false, "Rename hidden files and walk hidden directories")
+	flag.StringVar(&junk, "junk", "ignore", "Action for junk files like .DS_Store: ignore or delete")
+	flag.BoolVar(&dirs, "dirs", false, "Rename directories in their parent directories instead of files, deepest first")
 	flag.Parse()
 
+	os.Exit(run())
+}
+
+// run renames files and returns the exit code.
+func run() int {
+	if flag.Arg(0) == "undo" {
+		return undo(flag.Arg(1))
+	}
+
 	root := flag.Arg(0)
+	var nest *renfls.Nest
+	if flag.Arg(0) == "nest" {
+		var e error
+		if nest, root, e = parseNest(flag.Args()[1:]); e != nil {
+			fmt.Println(e)
+			return exitFatal
+		}
+	}
 	if root == "" {
 		fmt.Println("Input root directory name as command argument")
-		return
+		return exitFatal
 	}
 	if dest == "" {
 		dest = root
@@ -42,17 +170,243 @@ func main() {
 	if ext != "" {
 		exts = strings.Split(ext, separator)
 	}
+	var globs []string
+	if glob != "" {
+		globs = strings.Split(glob, separator)
+	}
 
 	// DEBUG: Copy test files
 	// createTestDir()
 
-	fmt.Println(root)
-	fmt.Println(dest)
+	condition := renfls.Condition{
+		Exts: exts, CaseSensitive: caseSensitive, Reg: reg, Globs: globs, Ignore: ignore,
+		Include: includes, Exclude: excludes, ExcludeDirs: []string{},
+	}
+	if excludeDir != "" {
+		condition.ExcludeDirs = strings.Split(excludeDir, separator)
+	}
+	if where != "" {
+		filter, e := renfls.ParseFilter(where)
+		if e != nil {
+			fmt.Println(e)
+			return exitFatal
+		}
+		condition.Filter = filter
+	}
+	if e := parseMetadata(&condition); e != nil {
+		fmt.Println(e)
+		return exitFatal
+	}
+	strategy, e := renfls.ParseCollision(onConflict)
+	if e != nil {
+		fmt.Println(e)
+		return exitFatal
+	}
+	dedupAction, e := renfls.ParseDedup(dedup)
+	if e != nil {
+		fmt.Println(e)
+		return exitFatal
+	}
+	walkOrder, e := renfls.ParseOrder(order)
+	if e != nil {
+		fmt.Println(e)
+		return exitFatal
+	}
+	placeMode, e := renfls.ParseMode(mode)
+	if e != nil {
+		fmt.Println(e)
+		return exitFatal
+	}
+	symlinkPolicy, e := renfls.ParseSymlinkPolicy(symlinks)
+	if e != nil {
+		fmt.Println(e)
+		return exitFatal
+	}
+	junkAction, e := renfls.ParseJunk(junk)
+	if e != nil {
+		fmt.Println(e)
+		return exitFatal
+	}
+	if reportFormat != "" {
+		if e := checkReportFormat(reportFormat); e != nil {
+			fmt.Println(e)
+			return exitFatal
+		}
+	}
+	var naming renfls.PathName
+	if pathName != "" {
+		if naming, e = renfls.ParsePathName(pathName); e != nil {
+			fmt.Println(e)
+			return exitFatal
+		}
+		naming.Separator = pathSep
+	}
+	// Directories would all be named by the root directory name otherwise.
+	if dirs && name == "" && pathName == "" {
+		fmt.Println("Input -name or -path-name to rename directories with -dirs")
+		return exitFatal
+	}
+	summary := &renfls.DedupSummary{}
 
-	condition := renfls.Condition{Exts: exts, Reg: reg, Ignore: ignore}
-	if e := renfls.WalkToRootSubDirName(root, dest, condition); e != nil {
+	opts := renfls.Options{
+		Transactional:   transactional,
+		ContinueOnError: continueOnError,
+		Workers:         workers,
+		Order:           walkOrder,
+		MinDepth:        minDepth,
+		MaxDepth:        maxDepth,
+		Mode:            placeMode,
+		Verify:          verify,
+		Symlinks:        symlinkPolicy,
+		Hidden:          hidden,
+		Junk:            junkAction,
+		Dirs:            dirs,
+		Name:            name,
+		PathName:        naming,
+		Collision: renfls.CollisionPolicy{
+			Strategy:      strategy,
+			SkipIdentical: skipIdentical,
+			SuffixFormat:  suffixFormat,
+			SuffixStart:   suffixStart,
+		},
+		Dedup: renfls.DedupPolicy{Action: dedupAction, Summary: summary},
+	}
+	if e := opts.Collision.Validate(); e != nil {
 		fmt.Println(e)
+		return exitFatal
+	}
+	if reportFormat != "" {
+		opts.Report = &renfls.Report{}
+		defer func() {
+			if e := printReport(os.Stdout, opts.Report, reportFormat); e != nil {
+				fmt.Println(e)
+			}
+		}()
+	}
+	if dryRun {
+		var operations []renfls.Operation
+		if nest != nil {
+			operations, e = opts.PlanRestructure(root, dest, *nest, condition)
+		} else if dirs {
+			operations, e = opts.Plan(root, dest, filepath.Base(root), condition)
+		} else {
+			operations, e = opts.PlanToRootSubDirName(root, dest, condition)
+		}
+		for _, o := range operations {
+			fmt.Println(o)
+		}
+		return exitCode(e)
+	}
+	if journalPath != "" {
+		// Paths in the journal must not depend on the working directory.
+		root, _ = filepath.Abs(root)
+		dest, _ = filepath.Abs(dest)
+		journal, e := renfls.CreateJournal(journalPath)
+		if e != nil {
+			fmt.Println(e)
+			return exitFatal
+		}
+		defer journal.Close()
+		opts.Journal = journal
+	}
+	if nest != nil {
+		e = opts.Restructure(root, dest, *nest, condition)
+	} else if dirs {
+		// Directories are named by the root directory name like files.
+		e = opts.WalkRename(root, dest, filepath.Base(root), condition)
+	} else {
+		e = opts.WalkToRootSubDirName(root, dest, condition)
+	}
+	if dedupAction != renfls.DedupOff {
+		printDedupSummary(dedupAction, summary)
+	}
+	return exitCode(e)
+}
+
+// printDedupSummary prints the duplicate files handled by an action.
+func printDedupSummary(action renfls.Dedup, summary *renfls.DedupSummary) {
+	switch action {
+	case renfls.DedupMove:
+		fmt.Printf("%d duplicate files moved, %d bytes can be reclaimed by removing them\n",
+			summary.Files, summary.Bytes)
+	case renfls.DedupLink:
+		fmt.Printf("%d duplicate files linked, %d bytes reclaimed\n", summary.Files, summary.Bytes)
+	case renfls.DedupSkip:
+		fmt.Printf("%d duplicate files skipped\n", summary.Files)
+	}
+}
+
+// parseNest parses the arguments of the nest subcommand
+// and returns the nest and the root directory.
+func parseNest(args []string) (*renfls.Nest, string, error) {
+	nest := &renfls.Nest{}
+	flags := flag.NewFlagSet("nest", flag.ContinueOnError)
+	flags.StringVar(&nest.Reg, "reg", "", "Regex with named groups like (?P<year>\\d{4}) to parse file names")
+	flags.StringVar(&nest.Pattern, "pattern", "", "Pattern like {year}-{month}-{day}_{title} to parse file names")
+	flags.StringVar(&nest.Dir, "dir", "", "Directories made from the groups like {year}/{month}/{title}")
+	flags.StringVar(&nest.Name, "name", "", "New file names made from the groups")
+	if e := flags.Parse(args); e != nil {
+		return nil, "", e
+	}
+	return nest, flags.Arg(0), nil
+}
+
+// parseMetadata sets the metadata flags to a condition.
+func parseMetadata(condition *renfls.Condition) error {
+	var e error
+	if minSize != "" {
+		if condition.MinSize, e = renfls.ParseSize(minSize); e != nil {
+			return e
+		}
+	}
+	if maxSize != "" {
+		if condition.MaxSize, e = renfls.ParseSize(maxSize); e != nil {
+			return e
+		}
+	}
+	if newer != "" {
+		if condition.ModifiedAfter, e = renfls.ParseTime(newer); e != nil {
+			return e
+		}
+	}
+	if older != "" {
+		if condition.ModifiedBefore, e = renfls.ParseTime(older); e != nil {
+			return e
+		}
+	}
+	if fileTypes != "" {
+		for _, name := range strings.Split(fileTypes, separator) {
+			t, e := renfls.ParseFileType(name)
+			if e != nil {
+				return e
+			}
+			condition.Types = append(condition.Types, t)
+		}
+	}
+	return nil
+}
+
+// exitCode prints an error and returns the exit code of it.
+func exitCode(e error) int {
+	if e == nil {
+		return exitOK
+	}
+	fmt.Println(e)
+
+	var multiErr *renfls.MultiError
+	var undoErr *renfls.UndoError
+	if errors.As(e, &multiErr) || errors.As(e, &undoErr) {
+		return exitPartial
+	}
+	return exitFatal
+}
+
+func undo(journalPath string) int {
+	if journalPath == "" {
+		fmt.Println("Input journal file name as command argument")
+		return exitFatal
 	}
+	return exitCode(renfls.Undo(journalPath))
 }
 
 func createTestDir() {
@@ -0,0 +1,91 @@
+// Copyright © 2017 shoarai
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/shoarai/renfls"
+)
+
+// reportEntry is renfls.ReportEntry with the error as a string.
+type reportEntry struct {
+	Old    string `json:"old"`
+	New    string `json:"new,omitempty"`
+	Rule   string `json:"rule,omitempty"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func toReportEntries(report *renfls.Report) []reportEntry {
+	entries := make([]reportEntry, len(report.Entries))
+	for i, e := range report.Entries {
+		entries[i] = reportEntry{
+			Old: e.Old, New: e.New, Rule: e.Rule, Status: string(e.Status), Reason: e.Reason,
+		}
+		if e.Err != nil {
+			entries[i].Error = e.Err.Error()
+		}
+	}
+	return entries
+}
+
+// reportFormats are the formats of reports.
+var reportFormats = []string{"table", "json", "csv"}
+
+// checkReportFormat returns an error if a format of reports is unknown.
+func checkReportFormat(format string) error {
+	for _, f := range reportFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown report format %q, want one of %s", format, strings.Join(reportFormats, ", "))
+}
+
+// printReport prints a report in a format of table, json or csv.
+func printReport(w io.Writer, report *renfls.Report, format string) error {
+	entries := toReportEntries(report)
+
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "STATUS\tOLD\tNEW\tRULE\tREASON")
+		for _, e := range entries {
+			reason := e.Reason
+			if e.Error != "" {
+				reason = e.Error
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Status, e.Old, e.New, e.Rule, reason)
+		}
+		fmt.Fprintf(tw, "\nrenamed: %d, skipped: %d, ignored: %d, failed: %d, deleted: %d\n",
+			report.Renamed, report.Skipped, report.Ignored, report.Failed, report.Deleted)
+		return tw.Flush()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Entries []reportEntry `json:"entries"`
+			Renamed int           `json:"renamed"`
+			Skipped int           `json:"skipped"`
+			Ignored int           `json:"ignored"`
+			Failed  int           `json:"failed"`
+			Deleted int           `json:"deleted"`
+		}{entries, report.Renamed, report.Skipped, report.Ignored, report.Failed, report.Deleted})
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"status", "old", "new", "rule", "reason", "error"})
+		for _, e := range entries {
+			cw.Write([]string{e.Status, e.Old, e.New, e.Rule, e.Reason, e.Error})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return checkReportFormat(format)
+}
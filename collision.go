@@ -0,0 +1,258 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Collision is a strategy for a new path that already exists.
+type Collision int
+
+// Strategies for new paths that already exist.
+const (
+	// CollisionSuffix adds a suffix to the new name.
+	CollisionSuffix Collision = iota
+	// CollisionSkip leaves the file as it is.
+	CollisionSkip
+	// CollisionOverwrite replaces the existing file.
+	CollisionOverwrite
+	// CollisionFail fails to rename the file.
+	CollisionFail
+	// CollisionKeepNewer replaces the existing file if the file is newer,
+	// or leaves the file as it is.
+	CollisionKeepNewer
+	// CollisionKeepLarger replaces the existing file if the file is larger,
+	// or leaves the file as it is.
+	CollisionKeepLarger
+	// CollisionRenameExisting adds a suffix to the existing file.
+	CollisionRenameExisting
+)
+
+var collisionNames = []string{
+	"suffix", "skip", "overwrite", "fail", "keep-newer", "keep-larger", "rename-existing",
+}
+
+func (c Collision) String() string {
+	if c < 0 || int(c) >= len(collisionNames) {
+		return fmt.Sprintf("Collision(%d)", int(c))
+	}
+	return collisionNames[c]
+}
+
+// ParseCollision returns the strategy of a name like "keep-newer".
+func ParseCollision(name string) (Collision, error) {
+	for i, n := range collisionNames {
+		if n == name {
+			return Collision(i), nil
+		}
+	}
+	return 0, fmt.Errorf("ParseCollision %q: unknown strategy, want one of %s",
+		name, strings.Join(collisionNames, ", "))
+}
+
+// CollisionPolicy is a policy for new paths that already exist.
+// The zero value adds suffixes "-2", "-3", ... to new names.
+type CollisionPolicy struct {
+	Strategy Collision
+	// SkipIdentical leaves the file as it is
+	// if it has the same content as the existing file.
+	SkipIdentical bool
+	// SuffixFormat is the format of a suffix with a number.
+	// The default is "-%d".
+	SuffixFormat string
+	// SuffixStart is the first number of suffixes. The default is 2.
+	SuffixStart int
+	// SuffixPadding is the width of numbers padded with zeros.
+	SuffixPadding int
+}
+
+// Validate returns an error if the suffix format of the policy
+// doesn't have exactly one verb for a number like "%d".
+func (policy CollisionPolicy) Validate() error {
+	format := policy.SuffixFormat
+	verbs := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		// Flags and widths like "%03d" are allowed.
+		for i++; i < len(format) && strings.IndexByte("+-# 0123456789", format[i]) >= 0; i++ {
+		}
+		if i < len(format) && format[i] == '%' {
+			continue
+		}
+		if i == len(format) || strings.IndexByte("bdoxX", format[i]) < 0 {
+			verbs = -1
+			break
+		}
+		verbs++
+	}
+	if format != "" && verbs != 1 {
+		return fmt.Errorf("SuffixFormat %q: want exactly one verb for a number like %%d", format)
+	}
+	return nil
+}
+
+func (policy CollisionPolicy) suffix() (format string, start int) {
+	format, start = policy.SuffixFormat, policy.SuffixStart
+	if format == "" {
+		format = fileSuffix
+	}
+	if start == 0 {
+		start = 2
+	}
+	if policy.SuffixPadding > 0 {
+		format = strings.Replace(format, "%d", fmt.Sprintf("%%0%dd", policy.SuffixPadding), 1)
+	}
+	return format, start
+}
+
+// skipError is the error of a file that is not renamed.
+type skipError struct {
+	path   string
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return fmt.Sprintf("%s is skipped: %s", e.path, e.reason)
+}
+
+// collide renames a file to a new path that already exists
+// by the collision policy.
+func (r *renamer) collide(oldPath, dest, newName, ext string) (string, error) {
+	policy := r.opts.Collision
+	newPath := filepath.Join(dest, newName) + ext
+
+	if policy.SkipIdentical {
+		same, e := r.sameContent(oldPath, newPath)
+		if e != nil {
+			return "", e
+		}
+		if same {
+			return "", &skipError{oldPath, newPath + " is identical"}
+		}
+	}
+
+	switch policy.Strategy {
+	case CollisionSuffix:
+		p, e := r.addSuffixIfExist(dest, newName, ext)
+		if e != nil {
+			return "", &RenameError{"Rename", oldPath, newPath, e}
+		}
+		return r.place(oldPath, p)
+	case CollisionSkip:
+		return "", &skipError{oldPath, newPath + " already exists"}
+	case CollisionOverwrite:
+		return r.overwrite(oldPath, newPath)
+	case CollisionFail:
+		return "", &RenameError{"Rename", oldPath, newPath, ErrDestExists}
+	case CollisionKeepNewer, CollisionKeepLarger:
+		oldInfo, e := r.fs.Stat(oldPath)
+		if e != nil {
+			return "", e
+		}
+		info, e := r.fs.Stat(newPath)
+		if e != nil {
+			return "", e
+		}
+		if policy.Strategy == CollisionKeepNewer && !oldInfo.ModTime().After(info.ModTime()) {
+			return "", &skipError{oldPath, newPath + " is newer"}
+		}
+		if policy.Strategy == CollisionKeepLarger && oldInfo.Size() <= info.Size() {
+			return "", &skipError{oldPath, newPath + " is larger"}
+		}
+		return r.overwrite(oldPath, newPath)
+	case CollisionRenameExisting:
+		p, e := r.addSuffixIfExist(dest, newName, ext)
+		if e != nil {
+			return "", &RenameError{"Rename", newPath, "", e}
+		}
+		if _, e := r.move(newPath, p); e != nil {
+			return "", e
+		}
+		return r.place(oldPath, newPath)
+	}
+	return "", fmt.Errorf("Rename %s: unknown collision strategy %s", oldPath, policy.Strategy)
+}
+
+// backupExt is the extension of the backups of overwritten files.
+const backupExt = ".renfls-bak"
+
+// overwrite replaces an existing file at a new path with a file.
+// If the operations are journaled or transactional, the existing file is
+// moved to a backup path first, so that undoing them restores it.
+func (r *renamer) overwrite(oldPath, newPath string) (string, error) {
+	if r.opts.Journal == nil && !r.opts.Transactional {
+		return r.place(oldPath, newPath)
+	}
+	if e := r.backup(newPath); e != nil {
+		return "", e
+	}
+	return r.place(oldPath, newPath)
+}
+
+// backup moves a file to a backup path in its directory
+// instead of removing it.
+func (r *renamer) backup(path string) error {
+	dir, file := filepath.Split(path)
+	backup, e := r.addSuffixIfExist(dir, file, backupExt)
+	if e != nil {
+		return &RenameError{"Rename", path, "", e}
+	}
+	if _, e := r.move(path, backup); e != nil {
+		return e
+	}
+	r.backups = append(r.backups, backup)
+	return nil
+}
+
+// sameContent returns whether two files have the same content.
+func (r *renamer) sameContent(path1, path2 string) (bool, error) {
+	info1, e := r.fs.Stat(path1)
+	if e != nil {
+		return false, e
+	}
+	info2, e := r.fs.Stat(path2)
+	if e != nil {
+		return false, e
+	}
+	if !info1.Mode().IsRegular() || !info2.Mode().IsRegular() || info1.Size() != info2.Size() {
+		return false, nil
+	}
+
+	f1, e := r.fs.Open(path1)
+	if e != nil {
+		return false, e
+	}
+	defer f1.Close()
+	f2, e := r.fs.Open(path2)
+	if e != nil {
+		return false, e
+	}
+	defer f2.Close()
+
+	const size = 32 * 1024
+	b1, b2 := make([]byte, size), make([]byte, size)
+	for {
+		n1, e1 := io.ReadFull(f1, b1)
+		n2, e2 := io.ReadFull(f2, b2)
+		if !bytes.Equal(b1[:n1], b2[:n2]) {
+			return false, nil
+		}
+		if e1 == io.EOF || e1 == io.ErrUnexpectedEOF {
+			return e2 == io.EOF || e2 == io.ErrUnexpectedEOF, nil
+		}
+		if e1 != nil {
+			return false, e1
+		}
+		if e2 != nil {
+			return false, e2
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestCollisionPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy renfls.CollisionPolicy
+		// Contents of root/dir/text.txt and the existing new.txt.
+		content, existing string
+		// older is true if root/dir/text.txt is older than new.txt.
+		older        bool
+		wantContents map[string]string
+		wantErr      bool
+	}{
+		{
+			renfls.CollisionPolicy{}, "a", "b", false,
+			map[string]string{"new.txt": "b", "new-2.txt": "a"}, false,
+		},
+		{
+			renfls.CollisionPolicy{SuffixFormat: " (%d)", SuffixStart: 1, SuffixPadding: 3},
+			"a", "b", false,
+			map[string]string{"new.txt": "b", "new (001).txt": "a"}, false,
+		},
+		{
+			renfls.CollisionPolicy{Strategy: renfls.CollisionSkip}, "a", "b", false,
+			map[string]string{"new.txt": "b", "root/dir/text.txt": "a"}, false,
+		},
+		{
+			renfls.CollisionPolicy{Strategy: renfls.CollisionOverwrite}, "a", "b", false,
+			map[string]string{"new.txt": "a"}, false,
+		},
+		{
+			renfls.CollisionPolicy{Strategy: renfls.CollisionFail}, "a", "b", false,
+			map[string]string{"new.txt": "b", "root/dir/text.txt": "a"}, true,
+		},
+		{
+			renfls.CollisionPolicy{Strategy: renfls.CollisionKeepNewer}, "a", "b", false,
+			map[string]string{"new.txt": "a"}, false,
+		},
+		{
+			renfls.CollisionPolicy{Strategy: renfls.CollisionKeepNewer}, "a", "b", true,
+			map[string]string{"new.txt": "b", "root/dir/text.txt": "a"}, false,
+		},
+		{
+			renfls.CollisionPolicy{Strategy: renfls.CollisionKeepLarger}, "aa", "b", false,
+			map[string]string{"new.txt": "aa"}, false,
+		},
+		{
+			renfls.CollisionPolicy{Strategy: renfls.CollisionKeepLarger}, "a", "bb", false,
+			map[string]string{"new.txt": "bb", "root/dir/text.txt": "a"}, false,
+		},
+		{
+			renfls.CollisionPolicy{Strategy: renfls.CollisionRenameExisting}, "a", "b", false,
+			map[string]string{"new.txt": "a", "new-2.txt": "b"}, false,
+		},
+		{
+			renfls.CollisionPolicy{SkipIdentical: true}, "a", "a", false,
+			map[string]string{"new.txt": "a", "root/dir/text.txt": "a"}, false,
+		},
+		{
+			renfls.CollisionPolicy{SkipIdentical: true}, "a", "b", false,
+			map[string]string{"new.txt": "b", "new-2.txt": "a"}, false,
+		},
+	} {
+		writeFile("root/dir/text.txt", test.content)
+		writeFile("new.txt", test.existing)
+		old := time.Now().Add(-time.Hour)
+		if test.older {
+			os.Chtimes("root/dir/text.txt", old, old)
+		} else {
+			os.Chtimes("new.txt", old, old)
+		}
+
+		opts := renfls.Options{Collision: test.policy}
+		err := opts.WalkRename("root", ".", "new", renfls.Condition{})
+		if (err != nil) != test.wantErr {
+			t.Errorf("WalkRename(%v) error = %v, want error %v\n", test.policy, err, test.wantErr)
+		}
+
+		for path, want := range test.wantContents {
+			if content := readFile(path); content != want {
+				t.Errorf("WalkRename(%v): %q has %q, want %q\n", test.policy, path, content, want)
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestParseCollision(t *testing.T) {
+	for _, c := range []renfls.Collision{
+		renfls.CollisionSuffix,
+		renfls.CollisionSkip,
+		renfls.CollisionOverwrite,
+		renfls.CollisionFail,
+		renfls.CollisionKeepNewer,
+		renfls.CollisionKeepLarger,
+		renfls.CollisionRenameExisting,
+	} {
+		got, err := renfls.ParseCollision(c.String())
+		if err != nil || got != c {
+			t.Errorf("ParseCollision(%q) = %v, %v, want %v\n", c.String(), got, err, c)
+		}
+	}
+	if _, err := renfls.ParseCollision("unknown"); err == nil {
+		t.Errorf("ParseCollision(%q) error = nil\n", "unknown")
+	}
+}
+
+func TestCollisionPolicyValidate(t *testing.T) {
+	for format, valid := range map[string]bool{
+		"": true, "-%d": true, " (%03d)": true, "_%x%%": true,
+		"_copy": false, "-%d-%d": false, "-%s": false, "-%": false,
+	} {
+		policy := renfls.CollisionPolicy{SuffixFormat: format}
+		if err := policy.Validate(); (err == nil) != valid {
+			t.Errorf("Validate(%q) = %v, want valid %v\n", format, err, valid)
+		}
+	}
+
+	// Files are not renamed by the invalid format.
+	createAlls("root", []string{"dir/text.txt"})
+	defer clearTestDir()
+	opts := renfls.Options{Collision: renfls.CollisionPolicy{SuffixFormat: "_copy"}}
+	if err := opts.WalkRename("root", "root", "dir", renfls.Condition{}); err == nil || !isFileExist("root/dir/text.txt") {
+		t.Errorf("WalkRename() = %v, want error without renaming\n", err)
+	}
+}
+
+func writeFile(path, content string) {
+	dir, _ := filepath.Split(path)
+	if dir != "" {
+		os.MkdirAll(dir, os.ModePerm)
+	}
+	ioutil.WriteFile(path, []byte(content), 0666)
+}
+
+func readFile(path string) string {
+	b, e := ioutil.ReadFile(path)
+	if e != nil {
+		return ""
+	}
+	return string(b)
+}
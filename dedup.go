@@ -0,0 +1,209 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"path/filepath"
+)
+
+const duplicatesDirName = "duplicates"
+
+// Dedup is an action for a file that has the same content as an existing file.
+type Dedup int
+
+// Actions for duplicate files.
+const (
+	// DedupOff renames duplicate files in the same way as other files.
+	DedupOff Dedup = iota
+	// DedupMove moves duplicate files to the duplicates directory.
+	DedupMove
+	// DedupLink renames duplicate files as hard links to the existing files.
+	DedupLink
+	// DedupSkip leaves duplicate files as they are.
+	DedupSkip
+)
+
+var dedupNames = []string{"off", "move", "link", "skip"}
+
+func (d Dedup) String() string {
+	if d < 0 || int(d) >= len(dedupNames) {
+		return fmt.Sprintf("Dedup(%d)", int(d))
+	}
+	return dedupNames[d]
+}
+
+// ParseDedup returns the action of a name like "link".
+func ParseDedup(name string) (Dedup, error) {
+	for i, n := range dedupNames {
+		if n == name {
+			return Dedup(i), nil
+		}
+	}
+	return 0, fmt.Errorf("ParseDedup %q: unknown action", name)
+}
+
+// DedupPolicy is a policy for files that have the same content as
+// the existing files with the new name or its suffixed names.
+type DedupPolicy struct {
+	Action Dedup
+	// Hash returns a hash to compare contents. The default is sha256.New.
+	Hash func() hash.Hash
+	// Dir is the directory to which DedupMove moves duplicate files.
+	// A relative path is from the destination directory.
+	// The default is "duplicates".
+	Dir string
+	// Summary is filled with the duplicates found if it is not nil.
+	Summary *DedupSummary
+}
+
+// DedupSummary is the summary of duplicate files.
+type DedupSummary struct {
+	// Files is the number of duplicate files handled by the action.
+	Files int
+	// Bytes is the size of the duplicate files,
+	// which is reclaimed if the action is DedupLink.
+	Bytes int64
+}
+
+// dedup handles a file if it has the same content as a file
+// that collides with the new name.
+// It returns false if the file is not a duplicate.
+func (r *renamer) dedup(oldPath, dest, newName, ext string) (string, bool, error) {
+	policy := r.opts.Dedup
+	if policy.Action == DedupOff {
+		return "", false, nil
+	}
+
+	existing, e := r.findDuplicate(oldPath, dest, newName, ext)
+	if e != nil || existing == "" {
+		return "", false, e
+	}
+	info, e := r.fs.Stat(oldPath)
+	if e != nil {
+		return "", false, e
+	}
+
+	newPath, e := r.handleDuplicate(oldPath, dest, newName, ext, existing)
+	if _, skipped := e.(*skipError); policy.Summary != nil && (e == nil || skipped) {
+		policy.Summary.Files++
+		policy.Summary.Bytes += info.Size()
+	}
+	return newPath, true, e
+}
+
+// handleDuplicate handles a file that has the same content as
+// an existing file by the action of the dedup policy.
+func (r *renamer) handleDuplicate(oldPath, dest, newName, ext, existing string) (string, error) {
+	policy := r.opts.Dedup
+	switch policy.Action {
+	case DedupMove:
+		dir := policy.Dir
+		if dir == "" {
+			dir = duplicatesDirName
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(dest, dir)
+		}
+		if e := mkdirAll(r.fs, dir); e != nil {
+			return "", e
+		}
+		_, file := filepath.Split(oldPath)
+		fileExt := r.ext(file)
+		p, e := r.addSuffixIfExist(dir, file[:len(file)-len(fileExt)], fileExt)
+		if e != nil {
+			return "", &RenameError{"Rename", oldPath, filepath.Join(dir, file), e}
+		}
+		return r.place(oldPath, p)
+	case DedupLink:
+		p, e := r.addSuffixIfExist(dest, newName, ext)
+		if e != nil {
+			return "", &RenameError{"Rename", oldPath, existing, e}
+		}
+		if _, e := r.place(oldPath, p); e != nil {
+			return "", e
+		}
+		if e := r.fs.Relink(existing, p); e != nil {
+			return "", &RenameError{"Link", existing, p, underlying(e)}
+		}
+		return p, nil
+	case DedupSkip:
+		return "", &skipError{oldPath, "duplicate of " + existing}
+	}
+	return "", fmt.Errorf("Rename %s: unknown dedup action %s", oldPath, policy.Action)
+}
+
+// findDuplicate returns the file that has the same content as a file
+// in the new name and its suffixed names that already exist.
+func (r *renamer) findDuplicate(oldPath, dest, newName, ext string) (string, error) {
+	info, e := r.fs.Stat(oldPath)
+	if e != nil || !info.Mode().IsRegular() {
+		return "", e
+	}
+
+	var sum []byte
+	path := filepath.Join(dest, newName)
+	format, start := r.opts.Collision.suffix()
+	for i := start - 1; i < math.MaxInt16; i++ {
+		p := path + ext
+		if i >= start {
+			p = path + fmt.Sprintf(format, i) + ext
+		}
+		existing, e := r.fs.Stat(p)
+		if e != nil {
+			return "", nil
+		}
+		if !existing.Mode().IsRegular() || existing.Size() != info.Size() {
+			continue
+		}
+
+		if sum == nil {
+			if sum, e = r.hash(oldPath); e != nil {
+				return "", e
+			}
+		}
+		existingSum, e := r.hash(p)
+		if e != nil {
+			return "", e
+		}
+		if bytes.Equal(sum, existingSum) {
+			return p, nil
+		}
+	}
+	return "", nil
+}
+
+// hash returns the hash of the content of a file.
+// The hashes are cached until the files are moved.
+func (r *renamer) hash(path string) ([]byte, error) {
+	if sum, ok := r.hashes[path]; ok {
+		return sum, nil
+	}
+
+	f, e := r.fs.Open(path)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+
+	newHash := r.opts.Dedup.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	h := newHash()
+	if _, e := io.Copy(h, f); e != nil {
+		return nil, e
+	}
+	sum := h.Sum(nil)
+	if r.hashes == nil {
+		r.hashes = map[string][]byte{}
+	}
+	r.hashes[path] = sum
+	return sum, nil
+}
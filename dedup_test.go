@@ -0,0 +1,112 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestDedup(t *testing.T) {
+	for _, test := range []struct {
+		action       renfls.Dedup
+		wantContents map[string]string
+		wantSameFile []string
+	}{
+		{
+			renfls.DedupMove,
+			map[string]string{
+				"new.txt": "a", "new-2.txt": "b", "new-3.txt": "c",
+				"duplicates/text2.txt": "b",
+			},
+			nil,
+		},
+		{
+			renfls.DedupLink,
+			map[string]string{
+				"new.txt": "a", "new-2.txt": "b", "new-3.txt": "c", "new-4.txt": "b",
+			},
+			[]string{"new-2.txt", "new-4.txt"},
+		},
+		{
+			renfls.DedupSkip,
+			map[string]string{
+				"new.txt": "a", "new-2.txt": "b", "new-3.txt": "c", "root/dir/text2.txt": "b",
+			},
+			nil,
+		},
+	} {
+		writeFile("new.txt", "a")
+		writeFile("new-2.txt", "b")
+		writeFile("root/dir/text1.txt", "c")
+		writeFile("root/dir/text2.txt", "b")
+
+		summary := &renfls.DedupSummary{}
+		opts := renfls.Options{Dedup: renfls.DedupPolicy{Action: test.action, Summary: summary}}
+		if err := opts.WalkRename("root", ".", "new", renfls.Condition{}); err != nil {
+			t.Errorf("WalkRename(%v) error: %s\n", test.action, err)
+		}
+
+		for path, want := range test.wantContents {
+			if content := readFile(path); content != want {
+				t.Errorf("WalkRename(%v): %q has %q, want %q\n", test.action, path, content, want)
+			}
+		}
+		if test.wantSameFile != nil {
+			info1, _ := os.Stat(test.wantSameFile[0])
+			info2, _ := os.Stat(test.wantSameFile[1])
+			if info1 == nil || info2 == nil || !os.SameFile(info1, info2) {
+				t.Errorf("WalkRename(%v): %v are not the same file\n", test.action, test.wantSameFile)
+			}
+		}
+		if summary.Files != 1 || summary.Bytes != 1 {
+			t.Errorf("WalkRename(%v): summary = %+v, want 1 file and 1 byte\n", test.action, summary)
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestDedupFailure(t *testing.T) {
+	writeFile("new.txt", "a")
+	writeFile("root/text.txt", "a")
+	defer clearTestDir()
+
+	// The duplicates directory can't be made under a file.
+	summary := &renfls.DedupSummary{}
+	policy := renfls.DedupPolicy{Action: renfls.DedupMove, Dir: "new.txt", Summary: summary}
+	if err := (renfls.Options{Dedup: policy}).WalkRename("root", ".", "new", renfls.Condition{}); err == nil {
+		t.Errorf("WalkRename() = nil, want error\n")
+	}
+	if summary.Files != 0 || summary.Bytes != 0 {
+		t.Errorf("WalkRename(): summary = %+v, want none\n", summary)
+	}
+}
+
+func TestDedupLinkUndo(t *testing.T) {
+	writeFile("n.txt", "a")
+	writeFile("root/sub/a.txt", "a")
+	defer clearTestDir()
+
+	journal, err := renfls.CreateJournal(journalPath)
+	if err != nil {
+		t.Fatalf("CreateJournal() error: %s\n", err)
+	}
+	opts := renfls.Options{Journal: journal, Dedup: renfls.DedupPolicy{Action: renfls.DedupLink}}
+	if err := opts.WalkRename("root", ".", "n", renfls.Condition{}); err != nil {
+		t.Errorf("WalkRename() error: %s\n", err)
+	}
+	journal.Close()
+	if err := renfls.Undo(journalPath); err != nil {
+		t.Errorf("Undo() error: %s\n", err)
+	}
+
+	// The restored file is not a hard link to the existing file.
+	info1, _ := os.Stat("n.txt")
+	info2, _ := os.Stat("root/sub/a.txt")
+	if info1 == nil || info2 == nil || os.SameFile(info1, info2) || readFile("root/sub/a.txt") != "a" {
+		t.Errorf("Undo(): root/sub/a.txt is not restored as an independent file\n")
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestDepth(t *testing.T) {
+	createAlls("root", []string{"dir1/a.jpg", "dir1/album/b.jpg", "dir1/album/nested/c.jpg"})
+
+	for _, test := range []struct {
+		minDepth, maxDepth int
+		want               []string
+	}{
+		{0, 1, []string{"root/ignore/dir1/a.jpg"}},
+		{2, 0, []string{"root/ignore/dir1/album/b.jpg", "root/ignore/dir1/album/nested/c.jpg"}},
+		{2, 2, []string{"root/ignore/dir1/album/b.jpg"}},
+	} {
+		for _, workers := range []int{1, 4} {
+			report := &renfls.Report{}
+			opts := renfls.Options{
+				Report: report, Workers: workers, MinDepth: test.minDepth, MaxDepth: test.maxDepth,
+			}
+			if _, err := opts.PlanToRootSubDirName("root", "root", renfls.Condition{}); err != nil {
+				t.Errorf("PlanToRootSubDirName() error: %s\n", err)
+			}
+			var renamed []string
+			for _, entry := range report.Entries {
+				if entry.Status == renfls.StatusRenamed {
+					renamed = append(renamed, entry.Old)
+				}
+			}
+			if fmt.Sprint(renamed) != fmt.Sprint(test.want) {
+				t.Errorf("PlanToRootSubDirName() with depth %d-%d and %d workers renamed %v, want %v\n",
+					test.minDepth, test.maxDepth, workers, renamed, test.want)
+			}
+		}
+	}
+
+	clearTestDir()
+}
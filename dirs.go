@@ -0,0 +1,95 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// walkRenameDirs renames the directories that match in a root directory
+// in their parent directories, deepest first.
+func (r *renamer) walkRenameDirs(root, newFileName string, match matcher) error {
+	if r.opts.Mode != ModeMove {
+		return fmt.Errorf("RenameDirs %s: directories can't be renamed by mode %s", root, r.opts.Mode)
+	}
+
+	// Directories are collected before renaming them,
+	// so that the paths of the deeper directories are kept.
+	var dirs []walkedFile
+	e := r.walk(root, r.walkDirsFunc(root, match, func(path string, info os.FileInfo, rule string) error {
+		dirs = append(dirs, walkedFile{path, info, rule})
+		return nil
+	}))
+	if e != nil {
+		return e
+	}
+	sep := string(filepath.Separator)
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return strings.Count(dirs[i].path, sep) > strings.Count(dirs[j].path, sep)
+	})
+
+	for _, dir := range dirs {
+		parent := filepath.Dir(dir.path)
+		// Directories have no extensions, so {name} is the whole name.
+		name := r.newNameExt(root, dir.path, dir.info, newFileName, "")
+		var newPath string
+		if filepath.Join(parent, name) == dir.path {
+			e = &skipError{dir.path, "already named"}
+		} else {
+			newPath, e = r.renameExt(dir.path, parent, name, "")
+		}
+		if e := r.renamed(dir.path, newPath, dir.rule, e); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// walkDirsFunc returns a function to walk a root that calls fn
+// for directories under the root that match and records the others.
+func (r *renamer) walkDirsFunc(root string, match matcher,
+	fn func(path string, info os.FileInfo, rule string) error) filepath.WalkFunc {
+	return func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			r.record(ReportEntry{Old: path, Status: StatusFailed, Err: err})
+			return r.fail(err)
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if skip, rule := r.excludeDir(rel); skip {
+			r.record(ReportEntry{Old: path, Rule: rule, Status: StatusIgnored})
+			return filepath.SkipDir
+		}
+
+		// Directories at the max depth are renamed but not walked.
+		var skip error
+		if r.opts.MaxDepth > 0 && depth(rel) >= r.opts.MaxDepth {
+			skip = filepath.SkipDir
+		}
+		if depth(rel) < r.opts.MinDepth {
+			rule := fmt.Sprintf("shallower than min depth %d", r.opts.MinDepth)
+			r.record(ReportEntry{Old: path, Rule: rule, Status: StatusIgnored})
+			return skip
+		}
+		ok, rule := match(rel, info)
+		if !ok {
+			r.record(ReportEntry{Old: path, Rule: rule, Status: StatusIgnored})
+			return skip
+		}
+		if err := fn(path, info, rule); err != nil {
+			return err
+		}
+		return skip
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestDirs(t *testing.T) {
+	for _, test := range []struct {
+		opts      renfls.Options
+		condition renfls.Condition
+		want      []string
+	}{
+		{renfls.Options{}, renfls.Condition{}, []string{
+			"root/root-a/a-b/b-c/file.txt", "root/root-a/a-d/file.txt", "root/root-a/a-b/.git/config",
+		}},
+		{renfls.Options{MaxDepth: 1}, renfls.Condition{}, []string{
+			"root/root-a/b/c/file.txt", "root/root-a/d/file.txt",
+		}},
+		{renfls.Options{MinDepth: 2}, renfls.Condition{Reg: "^[bc]$"}, []string{
+			"root/a/a-b/b-c/file.txt", "root/a/d/file.txt",
+		}},
+		// The dotted part of the names of directories is kept.
+		{renfls.Options{}, renfls.Condition{Reg: `\.`}, []string{
+			"root/root-trip.2017/file.txt", "root/root-trip.2018.tar.gz/file.txt",
+		}},
+	} {
+		createAlls("root", []string{
+			"a/b/c/file.txt", "a/d/file.txt", "a/b/.git/config",
+			"trip.2017/file.txt", "trip.2018.tar.gz/file.txt",
+		})
+
+		opts := test.opts
+		opts.Dirs = true
+		opts.Name = "{parent}-{name}"
+		if err := opts.WalkRename("root", "root", "", test.condition); err != nil {
+			t.Errorf("WalkRename(%+v) error: %s\n", test.opts, err)
+		}
+		for _, path := range test.want {
+			if !isFileExist(path) {
+				t.Errorf("WalkRename(%+v): %s doesn't exist\n", test.opts, path)
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestDirsAlreadyNamed(t *testing.T) {
+	createAlls("root", []string{"a/file.txt", "b/file.txt"})
+	defer clearTestDir()
+
+	report := &renfls.Report{}
+	opts := renfls.Options{Dirs: true, Report: report}
+	if err := opts.WalkRename("root", "root", "a", renfls.Condition{}); err != nil {
+		t.Errorf("WalkRename() error: %s\n", err)
+	}
+	if report.Renamed != 1 || report.Skipped != 1 || !isFileExist("root/a-2/file.txt") {
+		t.Errorf("WalkRename(): %d renamed and %d skipped, want 1 and 1\n", report.Renamed, report.Skipped)
+	}
+
+	opts = renfls.Options{Dirs: true, Mode: renfls.ModeCopy}
+	if err := opts.WalkRename("root", "root", "c", renfls.Condition{}); err == nil {
+		t.Errorf("WalkRename() with ModeCopy = nil, want error\n")
+	}
+}
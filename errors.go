@@ -0,0 +1,108 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Errors of renaming files.
+// ErrSourceNotFound and ErrDestNotFound match fs.ErrNotExist,
+// and ErrDestExists matches fs.ErrExist.
+var (
+	ErrSourceNotFound   error = &kindError{"source not found", fs.ErrNotExist}
+	ErrDestNotFound     error = &kindError{"destination not found", fs.ErrNotExist}
+	ErrDestExists       error = &kindError{"destination already exists", fs.ErrExist}
+	ErrSuffixExhausted        = errors.New("file suffixes are exhausted")
+	ErrChecksumMismatch       = errors.New("checksum of the copy doesn't match the original")
+)
+
+// kindError is an error that matches another error.
+type kindError struct {
+	msg  string
+	kind error
+}
+
+func (e *kindError) Error() string        { return e.msg }
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// RenameError records an error and the operation and paths that caused it.
+// New is empty if the error is not of the new path.
+type RenameError struct {
+	Op  string
+	Old string
+	New string
+	Err error
+}
+
+func (e *RenameError) Error() string {
+	if e.New == "" {
+		return fmt.Sprintf("%s %s: %s", e.Op, e.Old, e.Err)
+	}
+	return fmt.Sprintf("%s %s %s: %s", e.Op, e.Old, e.New, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RenameError) Unwrap() error {
+	return e.Err
+}
+
+// errorNotExist returns the error of a path that doesn't exist.
+// kind is ErrSourceNotFound or ErrDestNotFound.
+func errorNotExist(op, old, new string, kind, e error) error {
+	return &RenameError{op, old, new, fmt.Errorf("%w: %w", kind, underlying(e))}
+}
+
+// underlying returns the underlying error of *os.PathError and *os.LinkError
+// because RenameError has their paths.
+func underlying(e error) error {
+	switch e := e.(type) {
+	case *os.PathError:
+		return e.Err
+	case *os.LinkError:
+		return e.Err
+	}
+	return e
+}
+
+// MultiError is the failures of files
+// that are collected when Options.ContinueOnError is true.
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d files failed\n%s", len(e.Errors), strings.Join(msgs, "\n"))
+}
+
+// Unwrap returns the failures for errors.Is and errors.As.
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// fail returns the failure of a file,
+// or collects it and returns nil if the options continue on error.
+func (r *renamer) fail(e error) error {
+	if !r.opts.ContinueOnError {
+		return e
+	}
+	r.errs = append(r.errs, e)
+	return nil
+}
+
+// result returns the result of a batch with the collected failures.
+func (r *renamer) result(e error) error {
+	if e != nil || len(r.errs) == 0 {
+		return e
+	}
+	return &MultiError{r.errs}
+}
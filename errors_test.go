@@ -0,0 +1,114 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestContinueOnError(t *testing.T) {
+	// The new names of files with the long extension are too long.
+	longExt := "." + strings.Repeat("x", 60)
+	dir := strings.Repeat("d", 200)
+	createAlls("root", []string{dir + "/a" + longExt, dir + "/b.txt", dir + "/c" + longExt})
+
+	opts := renfls.Options{ContinueOnError: true}
+	err := opts.WalkToRootSubDirName("root", "root", renfls.Condition{})
+
+	var multiErr *renfls.MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("WalkToRootSubDirName() = %v, want *MultiError\n", err)
+	}
+	if len(multiErr.Errors) != 2 {
+		t.Errorf("WalkToRootSubDirName() errors = %v, want 2 errors\n", multiErr.Errors)
+	}
+	var renameErr *renfls.RenameError
+	if !errors.As(err, &renameErr) || !errors.Is(err, syscall.ENAMETOOLONG) {
+		t.Errorf("WalkToRootSubDirName() = %v, want *RenameError\n", err)
+	}
+	if !isFileExist("root/" + dir + ".txt") {
+		t.Errorf("The file after the failure didn't be renamed.\n")
+	}
+
+	clearTestDir()
+}
+
+func TestStopOnError(t *testing.T) {
+	longExt := "." + strings.Repeat("x", 60)
+	dir := strings.Repeat("d", 200)
+	createAlls("root", []string{dir + "/a" + longExt, dir + "/b.txt"})
+
+	err := renfls.WalkToRootSubDirName("root", "root", renfls.Condition{})
+
+	var multiErr *renfls.MultiError
+	if err == nil || errors.As(err, &multiErr) {
+		t.Errorf("WalkToRootSubDirName() = %v, want the first error\n", err)
+	}
+	if isExist("root/" + dir + ".txt") {
+		t.Errorf("The file after the failure is renamed.\n")
+	}
+
+	clearTestDir()
+}
+
+func TestTypedErrors(t *testing.T) {
+	createAlls("root", []string{"dir/text.txt", "dir/image.jpg"})
+	createAll("new.txt")
+	createAll("new-32766.txt")
+
+	for _, test := range []struct {
+		rename   func() error
+		want     error
+		wantKind error
+	}{
+		{
+			func() error { _, e := renfls.Rename("none.txt", ".", "new"); return e },
+			renfls.ErrSourceNotFound, fs.ErrNotExist,
+		},
+		{
+			func() error { _, e := renfls.Rename("root/dir/text.txt", "none", "new"); return e },
+			renfls.ErrDestNotFound, fs.ErrNotExist,
+		},
+		{
+			func() error { return renfls.WalkRename("none", ".", "new", renfls.Condition{}) },
+			renfls.ErrSourceNotFound, fs.ErrNotExist,
+		},
+		{
+			func() error {
+				opts := renfls.Options{Collision: renfls.CollisionPolicy{Strategy: renfls.CollisionFail}}
+				_, e := opts.Rename("root/dir/text.txt", ".", "new")
+				return e
+			},
+			renfls.ErrDestExists, fs.ErrExist,
+		},
+		{
+			func() error {
+				opts := renfls.Options{Collision: renfls.CollisionPolicy{SuffixStart: 32766}}
+				_, e := opts.Rename("root/dir/text.txt", ".", "new")
+				return e
+			},
+			renfls.ErrSuffixExhausted, nil,
+		},
+	} {
+		err := test.rename()
+
+		var renameErr *renfls.RenameError
+		if !errors.As(err, &renameErr) {
+			t.Errorf("error = %v, want *RenameError\n", err)
+		}
+		if !errors.Is(err, test.want) {
+			t.Errorf("error = %v, want %v\n", err, test.want)
+		}
+		if test.wantKind != nil && !errors.Is(err, test.wantKind) {
+			t.Errorf("error = %v, want %v\n", err, test.wantKind)
+		}
+	}
+
+	clearTestDir()
+}
@@ -0,0 +1,117 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// EXIF tags of dates.
+const (
+	tagExifIFD          = 0x8769
+	tagDateTime         = 0x0132
+	tagDateTimeOriginal = 0x9003
+)
+
+var errNoExifDate = errors.New("no EXIF date")
+
+// readExifDate reads the date when a JPEG image was taken.
+// It returns DateTimeOriginal, or DateTime if the image doesn't have it.
+func readExifDate(r io.Reader) (time.Time, error) {
+	tiff, e := readExifSegment(r)
+	if e != nil {
+		return time.Time{}, e
+	}
+	if len(tiff) < 8 {
+		return time.Time{}, errNoExifDate
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return time.Time{}, errNoExifDate
+	}
+
+	ifd0 := readIFD(tiff, order, order.Uint32(tiff[4:8]))
+	if off, ok := ifd0[tagExifIFD]; ok {
+		exif := readIFD(tiff, order, order.Uint32(off))
+		if t, ok := exifTime(tiff, order, exif[tagDateTimeOriginal]); ok {
+			return t, nil
+		}
+	}
+	if t, ok := exifTime(tiff, order, ifd0[tagDateTime]); ok {
+		return t, nil
+	}
+	return time.Time{}, errNoExifDate
+}
+
+// readExifSegment returns the TIFF data in the APP1 segment of a JPEG image.
+func readExifSegment(r io.Reader) ([]byte, error) {
+	var soi [2]byte
+	if _, e := io.ReadFull(r, soi[:]); e != nil || soi != [2]byte{0xFF, 0xD8} {
+		return nil, errNoExifDate
+	}
+
+	for {
+		var marker [4]byte
+		if _, e := io.ReadFull(r, marker[:]); e != nil || marker[0] != 0xFF {
+			return nil, errNoExifDate
+		}
+		// Start of scan has no more metadata.
+		if marker[1] == 0xDA {
+			return nil, errNoExifDate
+		}
+		size := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if size < 0 {
+			return nil, errNoExifDate
+		}
+		data := make([]byte, size)
+		if _, e := io.ReadFull(r, data); e != nil {
+			return nil, errNoExifDate
+		}
+		if marker[1] == 0xE1 && bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+			return data[6:], nil
+		}
+	}
+}
+
+// readIFD returns the values or the offsets of the entries in an IFD by tags.
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) map[uint16][]byte {
+	entries := map[uint16][]byte{}
+	if int(offset)+2 > len(tiff) {
+		return entries
+	}
+	n := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < n; i++ {
+		p := int(offset) + 2 + i*12
+		if p+12 > len(tiff) {
+			break
+		}
+		entries[order.Uint16(tiff[p:])] = tiff[p+8 : p+12]
+	}
+	return entries
+}
+
+// exifTime parses an ASCII date at the offset in an IFD entry.
+func exifTime(tiff []byte, order binary.ByteOrder, value []byte) (time.Time, bool) {
+	if value == nil {
+		return time.Time{}, false
+	}
+	p := int(order.Uint32(value))
+	if p+len(exifTimeLayout) > len(tiff) {
+		return time.Time{}, false
+	}
+	t, e := time.ParseInLocation(exifTimeLayout, string(tiff[p:p+len(exifTimeLayout)]), time.Local)
+	return t, e == nil
+}
@@ -0,0 +1,64 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DefaultCompoundExts is the compound extensions kept as a whole
+// when Options.CompoundExts is nil.
+var DefaultCompoundExts = []string{"tar.gz", "tar.bz2", "tar.xz", "tar.zst", "tar.lz", "tar.lzma"}
+
+// splitExt returns the extension of a file name with the leading dot.
+// A compound extension like ".tar.gz" is returned as a whole,
+// and a dotfile like ".bashrc" has no extension.
+func splitExt(file string, compounds []string) string {
+	lower := strings.ToLower(file)
+	for _, ext := range compounds {
+		ext = "." + strings.ToLower(strings.TrimPrefix(ext, "."))
+		if len(lower) > len(ext) && strings.HasSuffix(lower, ext) {
+			return file[len(file)-len(ext):]
+		}
+	}
+	ext := filepath.Ext(file)
+	if strings.TrimLeft(file[:len(file)-len(ext)], ".") == "" {
+		return ""
+	}
+	return ext
+}
+
+// ext returns the extension of a file name by the options.
+func (r *renamer) ext(file string) string {
+	compounds := r.opts.CompoundExts
+	if compounds == nil {
+		compounds = DefaultCompoundExts
+	}
+	return splitExt(file, compounds)
+}
+
+// hasExt returns whether a file name ends with any of extensions
+// like "jpg" or "tar.gz". An empty extension matches files without extensions.
+func hasExt(file string, exts []string, caseSensitive bool) bool {
+	if !caseSensitive {
+		file = strings.ToLower(file)
+	}
+	for _, ext := range exts {
+		ext = strings.TrimPrefix(ext, ".")
+		if !caseSensitive {
+			ext = strings.ToLower(ext)
+		}
+		if ext == "" {
+			if splitExt(file, nil) == "" {
+				return true
+			}
+			continue
+		}
+		if len(file) > len(ext)+1 && strings.HasSuffix(file, "."+ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestExts(t *testing.T) {
+	for _, test := range []struct {
+		condition renfls.Condition
+		want      map[string]bool
+	}{
+		{
+			renfls.Condition{Exts: []string{"jpg", "tar.gz"}},
+			map[string]bool{"new.JPG": true, "new.tar.gz": true, "new.gz": false, "new.tgz": false},
+		},
+		{
+			renfls.Condition{Exts: []string{"jpg", "gz"}, CaseSensitive: true},
+			map[string]bool{"new.JPG": false, "new.tar.gz": true, "new.tgz": false},
+		},
+	} {
+		createAlls("root", []string{"dir/image.JPG", "dir/archive.tar.gz", "dir/archive.tgz"})
+
+		if err := renfls.WalkRename("root", ".", "new", test.condition); err != nil {
+			t.Errorf("WalkRename(%+v) error: %s\n", test.condition, err)
+		}
+		for path, want := range test.want {
+			if isFileExist(path) != want {
+				t.Errorf("WalkRename(%+v): %s exists = %v, want %v\n", test.condition, path, !want, want)
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestCompoundExts(t *testing.T) {
+	createAlls("root", []string{"dir/a.tar.gz", "dir/b.TAR.BZ2", "dir/c.gz", "dir/d.min.js"})
+
+	opts := renfls.Options{CompoundExts: append(renfls.DefaultCompoundExts, "min.js")}
+	if err := opts.WalkToRootDirName("root/dir", "root", renfls.Condition{}); err != nil {
+		t.Errorf("WalkToRootDirName() error: %s\n", err)
+	}
+	for _, path := range []string{"root/dir.tar.gz", "root/dir.TAR.BZ2", "root/dir.gz", "root/dir.min.js"} {
+		if !isFileExist(path) {
+			t.Errorf("WalkToRootDirName(): %s doesn't exist\n", path)
+		}
+	}
+
+	clearTestDir()
+}
@@ -0,0 +1,13 @@
+// Copyright © 2017 shoarai
+
+//go:build !unix
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import "os"
+
+// fileID returns false because files have no inodes on the platform.
+func fileID(info os.FileInfo) (interface{}, bool) {
+	return nil, false
+}
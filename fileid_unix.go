@@ -0,0 +1,20 @@
+// Copyright © 2017 shoarai
+
+//go:build unix
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns the device and the inode of a file.
+func fileID(info os.FileInfo) (interface{}, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, false
+	}
+	return [2]uint64{uint64(st.Dev), uint64(st.Ino)}, true
+}
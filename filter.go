@@ -0,0 +1,539 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter decides whether a file needs to be renamed.
+// Filters made by this package have String methods
+// that return expressions parsed by ParseFilter.
+type Filter interface {
+	Match(file File) bool
+}
+
+// File is a file to be filtered.
+type File struct {
+	// Path is the slash-separated path relative to the root.
+	Path string
+	Info os.FileInfo
+}
+
+// FilterFunc is a function used as a Filter.
+type FilterFunc func(file File) bool
+
+// Match returns f(file).
+func (f FilterFunc) Match(file File) bool { return f(file) }
+
+// filterString returns the expression of a filter for reports.
+func filterString(filter Filter) string {
+	if s, ok := filter.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return "condition"
+}
+
+type andFilter []Filter
+type orFilter []Filter
+type notFilter struct{ Filter }
+
+// And returns a filter that matches files matching all the filters.
+func And(filters ...Filter) Filter { return andFilter(filters) }
+
+// Or returns a filter that matches files matching any of the filters.
+func Or(filters ...Filter) Filter { return orFilter(filters) }
+
+// Not returns a filter that matches files not matching a filter.
+func Not(filter Filter) Filter { return notFilter{filter} }
+
+func (filters andFilter) Match(file File) bool {
+	for _, f := range filters {
+		if !f.Match(file) {
+			return false
+		}
+	}
+	return true
+}
+
+func (filters orFilter) Match(file File) bool {
+	for _, f := range filters {
+		if f.Match(file) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f notFilter) Match(file File) bool { return !f.Filter.Match(file) }
+
+func (filters andFilter) String() string { return joinFilters(filters, " and ") }
+func (filters orFilter) String() string  { return joinFilters(filters, " or ") }
+func (f notFilter) String() string       { return "not " + groupFilter(f.Filter) }
+
+func joinFilters(filters []Filter, sep string) string {
+	s := make([]string, len(filters))
+	for i, f := range filters {
+		s[i] = groupFilter(f)
+	}
+	return strings.Join(s, sep)
+}
+
+// groupFilter returns the expression of a filter in parentheses
+// if it has operators.
+func groupFilter(filter Filter) string {
+	switch filter.(type) {
+	case andFilter, orFilter:
+		return "(" + filterString(filter) + ")"
+	}
+	return filterString(filter)
+}
+
+// comparison is a filter that compares a field of files with values.
+type comparison struct {
+	field  string
+	op     string
+	values []string
+	match  func(file File) bool
+}
+
+func (c *comparison) Match(file File) bool { return c.match(file) }
+
+func (c *comparison) String() string {
+	values := make([]string, len(c.values))
+	for i, v := range c.values {
+		values[i] = quoteValue(v)
+	}
+	if c.op == "in" {
+		return fmt.Sprintf("%s in (%s)", c.field, strings.Join(values, ","))
+	}
+	return fmt.Sprintf("%s %s %s", c.field, c.op, values[0])
+}
+
+// quoteValue quotes a value if it can't be parsed as a word.
+func quoteValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\n()=!<>~,\"'") || isKeyword(v) {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// Fields of files compared by Where.
+var (
+	stringFields = map[string]func(File) string{
+		"name": func(f File) string { return f.Info.Name() },
+		"path": func(f File) string { return f.Path },
+		"ext": func(f File) string {
+			return strings.ToLower(strings.TrimPrefix(splitExt(f.Info.Name(), DefaultCompoundExts), "."))
+		},
+		"type": func(f File) string { return fileTypeOf(f.Info).String() },
+	}
+	intFields = map[string]func(File) int64{
+		"size":  func(f File) int64 { return f.Info.Size() },
+		"mtime": func(f File) int64 { return f.Info.ModTime().UnixNano() },
+		"mode":  func(f File) int64 { return int64(f.Info.Mode().Perm()) },
+	}
+)
+
+// Where returns a filter that compares a field of files with values.
+//
+// The fields are name, path (relative to the root),
+// ext (without a dot, matched in the same way as Condition.Exts),
+// type (a FileType like regular, dir or symlink), size (like 100k, 2M or 1G),
+// mtime (like 2017-01-02 or 2017-01-02T15:04:05) and mode (octal like 644).
+// The operators are =, != and in for all fields, ~ and !~ (regular expressions)
+// for name, path, ext and type, and <, <=, > and >= for size, mtime and mode.
+// "in" takes one or more values and the other operators take one.
+func Where(field, op string, values ...string) (Filter, error) {
+	c := &comparison{field: field, op: op, values: values}
+	if len(values) == 0 || op != "in" && len(values) > 1 {
+		return nil, fmt.Errorf("Where %s %s: wrong number of values %d", field, op, len(values))
+	}
+
+	if match, ok, e := setMatch(field, op, values); ok {
+		if e != nil {
+			return nil, fmt.Errorf("Where %s: %s", c, e)
+		}
+		c.match = match
+		return c, nil
+	}
+	if get, ok := stringFields[field]; ok {
+		match, e := stringMatch(op, values)
+		if e != nil {
+			return nil, fmt.Errorf("Where %s: %s", c, e)
+		}
+		c.match = func(f File) bool { return match(get(f)) }
+		return c, nil
+	}
+	if get, ok := intFields[field]; ok {
+		nums := make([]int64, len(values))
+		for i, v := range values {
+			n, e := parseFieldValue(field, v)
+			if e != nil {
+				return nil, fmt.Errorf("Where %s: %s", c, e)
+			}
+			nums[i] = n
+		}
+		match, e := intMatch(op, nums)
+		if e != nil {
+			return nil, fmt.Errorf("Where %s: %s", c, e)
+		}
+		c.match = func(f File) bool { return match(get(f)) }
+		return c, nil
+	}
+	return nil, fmt.Errorf("Where %s: unknown field %q", c, field)
+}
+
+// setMatch returns the match of =, != and in for ext and type,
+// which match files by extensions and file types.
+// ok is false for the other fields and operators.
+func setMatch(field, op string, values []string) (_ func(File) bool, ok bool, _ error) {
+	if op != "=" && op != "!=" && op != "in" {
+		return nil, false, nil
+	}
+	var match func(File) bool
+	switch field {
+	case "ext":
+		match = func(f File) bool { return hasExt(f.Info.Name(), values, false) }
+	case "type":
+		types := make([]FileType, len(values))
+		for i, v := range values {
+			t, e := ParseFileType(v)
+			if e != nil {
+				return nil, true, e
+			}
+			types[i] = t
+		}
+		match = func(f File) bool {
+			for _, t := range types {
+				if t.match(f.Info) {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return nil, false, nil
+	}
+	if op == "!=" {
+		return func(f File) bool { return !match(f) }, true, nil
+	}
+	return match, true, nil
+}
+
+func stringMatch(op string, values []string) (func(string) bool, error) {
+	switch op {
+	case "=":
+		return func(s string) bool { return s == values[0] }, nil
+	case "!=":
+		return func(s string) bool { return s != values[0] }, nil
+	case "in":
+		return func(s string) bool { return contains(values, s) }, nil
+	case "~", "!~":
+		reg, e := regexp.Compile(values[0])
+		if e != nil {
+			return nil, e
+		}
+		want := op == "~"
+		return func(s string) bool { return reg.MatchString(s) == want }, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+func intMatch(op string, nums []int64) (func(int64) bool, error) {
+	switch op {
+	case "=":
+		return func(n int64) bool { return n == nums[0] }, nil
+	case "!=":
+		return func(n int64) bool { return n != nums[0] }, nil
+	case "<":
+		return func(n int64) bool { return n < nums[0] }, nil
+	case "<=":
+		return func(n int64) bool { return n <= nums[0] }, nil
+	case ">":
+		return func(n int64) bool { return n > nums[0] }, nil
+	case ">=":
+		return func(n int64) bool { return n >= nums[0] }, nil
+	case "in":
+		return func(n int64) bool {
+			for _, num := range nums {
+				if n == num {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+func parseFieldValue(field, value string) (int64, error) {
+	switch field {
+	case "size":
+		return ParseSize(value)
+	case "mtime":
+		t, e := ParseTime(value)
+		return t.UnixNano(), e
+	case "mode":
+		return strconv.ParseInt(value, 8, 64)
+	}
+	return 0, fmt.Errorf("unknown field %q", field)
+}
+
+// ParseSize parses a size like 100, 100k, 10MB or 1G in bytes.
+// The units are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	unit := int64(1)
+	num := strings.TrimRight(s, "bB")
+	if n := len(num); n > 0 {
+		switch num[n-1] {
+		case 'k', 'K':
+			unit = 1 << 10
+		case 'm', 'M':
+			unit = 1 << 20
+		case 'g', 'G':
+			unit = 1 << 30
+		case 't', 'T':
+			unit = 1 << 40
+		}
+		if unit > 1 {
+			num = num[:n-1]
+		}
+	}
+	n, e := strconv.ParseInt(num, 10, 64)
+	if e != nil {
+		return 0, fmt.Errorf("ParseSize %q: invalid size", s)
+	}
+	return n * unit, nil
+}
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseTime parses a time like 2017-01-02 or 2017-01-02T15:04:05 in local time.
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, e := time.ParseInLocation(layout, s, time.Local); e == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("ParseTime %q: invalid time, want 2006-01-02 or 2006-01-02T15:04:05", s)
+}
+
+// ParseFilter parses a filter expression like
+//
+//	ext in (jpg,png) and size > 100k and not name ~ "^thumb"
+//
+// Comparisons of Where are combined with "and", "or", "not" and parentheses.
+// Values with spaces or operators are quoted with double quotes.
+func ParseFilter(expr string) (Filter, error) {
+	tokens, e := tokenize(expr)
+	if e != nil {
+		return nil, fmt.Errorf("ParseFilter %q: %s", expr, e)
+	}
+	p := &filterParser{tokens: tokens}
+	filter, e := p.parseOr()
+	if e == nil && p.pos < len(p.tokens) {
+		e = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if e != nil {
+		return nil, fmt.Errorf("ParseFilter %q: %s", expr, e)
+	}
+	return filter, nil
+}
+
+// token is a word, a quoted string, an operator or a punctuation.
+type token struct {
+	text   string
+	quoted bool
+}
+
+const filterOpChars = "=!<>~"
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, token{text: string(c)})
+			i++
+		case strings.IndexByte(filterOpChars, c) >= 0:
+			j := i + 1
+			for j < len(expr) && strings.IndexByte(filterOpChars, expr[j]) >= 0 {
+				j++
+			}
+			tokens = append(tokens, token{text: expr[i:j]})
+			i = j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(expr) && expr[j] != c {
+				if expr[j] == '\\' && c == '"' {
+					j++
+				}
+				j++
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("unterminated string %s", expr[i:])
+			}
+			text := expr[i+1 : j]
+			if c == '"' {
+				var e error
+				if text, e = strconv.Unquote(expr[i : j+1]); e != nil {
+					return nil, fmt.Errorf("invalid string %s", expr[i:j+1])
+				}
+			}
+			tokens = append(tokens, token{text: text, quoted: true})
+			i = j + 1
+		default:
+			j := i
+			for j < len(expr) && !strings.ContainsRune(" \t\n(),\"'"+filterOpChars, rune(expr[j])) {
+				j++
+			}
+			tokens = append(tokens, token{text: expr[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func isKeyword(s string) bool {
+	switch strings.ToLower(s) {
+	case "and", "or", "not", "in":
+		return true
+	}
+	return false
+}
+
+// filterParser parses tokens of a filter expression.
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+// accept consumes the next token if it is a keyword or a punctuation.
+func (p *filterParser) accept(text string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted &&
+		strings.EqualFold(p.tokens[p.pos].text, text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) next() (token, error) {
+	if p.pos >= len(p.tokens) {
+		return token{}, fmt.Errorf("unexpected end")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	filters, e := p.parseList("or", p.parseAnd)
+	if e != nil {
+		return nil, e
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return Or(filters...), nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	filters, e := p.parseList("and", p.parseNot)
+	if e != nil {
+		return nil, e
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return And(filters...), nil
+}
+
+// parseList parses filters separated by a keyword.
+func (p *filterParser) parseList(sep string, parse func() (Filter, error)) ([]Filter, error) {
+	var filters []Filter
+	for {
+		f, e := parse()
+		if e != nil {
+			return nil, e
+		}
+		filters = append(filters, f)
+		if !p.accept(sep) {
+			return filters, nil
+		}
+	}
+}
+
+func (p *filterParser) parseNot() (Filter, error) {
+	if p.accept("not") {
+		f, e := p.parseNot()
+		if e != nil {
+			return nil, e
+		}
+		return Not(f), nil
+	}
+	if p.accept("(") {
+		f, e := p.parseOr()
+		if e != nil {
+			return nil, e
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return f, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (Filter, error) {
+	field, e := p.next()
+	if e != nil {
+		return nil, e
+	}
+	op, e := p.next()
+	if e != nil {
+		return nil, e
+	}
+	opText := op.text
+	if strings.EqualFold(opText, "in") {
+		opText = "in"
+	} else if opText == "==" {
+		opText = "="
+	}
+
+	var values []string
+	if opText == "in" {
+		if !p.accept("(") {
+			return nil, fmt.Errorf("missing ( after in")
+		}
+		for {
+			v, e := p.next()
+			if e != nil {
+				return nil, e
+			}
+			values = append(values, v.text)
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, fmt.Errorf("missing , or ) in the values of %s", field.text)
+			}
+		}
+	} else {
+		v, e := p.next()
+		if e != nil {
+			return nil, e
+		}
+		values = []string{v.text}
+	}
+	return Where(strings.ToLower(field.text), opText, values...)
+}
@@ -0,0 +1,90 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestParseFilter(t *testing.T) {
+	writeFile("root/a.jpg", strings.Repeat("a", 2048))
+	writeFile("root/thumb_a.jpg", strings.Repeat("a", 2048))
+	writeFile("root/b.png", "b")
+	writeFile("root/dir/c.txt", strings.Repeat("c", 2048))
+	writeFile("root/dir/D.JPG", "d")
+	writeFile("root/dir/e.tar.gz", "e")
+
+	for _, test := range []struct {
+		expr string
+		want []string
+	}{
+		{`ext in (jpg,png) and size > 1k and not name ~ "^thumb"`, []string{"root/a.jpg"}},
+		{`ext = png or (path ~ "^dir/" and size >= 2k)`, []string{"root/b.png", "root/dir/c.txt"}},
+		{`not (ext = jpg or ext = png or ext = gz)`, []string{"root/dir/c.txt"}},
+		{`type = regular and mtime > 2000-01-01 and size < 2k and ext != jpg`, []string{"root/b.png", "root/dir/e.tar.gz"}},
+		{`ext in (jpg, tar.gz) and path ~ "^dir/"`, []string{"root/dir/D.JPG", "root/dir/e.tar.gz"}},
+		{`ext ~ "^tar\\." or type in (symlink, dir)`, []string{"root/dir/e.tar.gz"}},
+	} {
+		filter, err := renfls.ParseFilter(test.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) error: %s\n", test.expr, err)
+			continue
+		}
+
+		report := &renfls.Report{}
+		opts := renfls.Options{Report: report}
+		if _, err := opts.Plan("root", ".", "new", renfls.Condition{Filter: filter}); err != nil {
+			t.Errorf("Plan(%q) error: %s\n", test.expr, err)
+		}
+		var renamed []string
+		for _, entry := range report.Entries {
+			if entry.Status == renfls.StatusRenamed {
+				renamed = append(renamed, entry.Old)
+			}
+		}
+		if strings.Join(renamed, ",") != strings.Join(test.want, ",") {
+			t.Errorf("Plan(%q) renamed %v, want %v\n", test.expr, renamed, test.want)
+		}
+
+		// The expression of the filter is parsed to the same filter.
+		again, err := renfls.ParseFilter(fmt.Sprint(filter))
+		if err != nil || fmt.Sprint(again) != fmt.Sprint(filter) {
+			t.Errorf("ParseFilter(%q) = %v, %v\n", fmt.Sprint(filter), again, err)
+		}
+	}
+
+	clearTestDir()
+}
+
+func TestParseFilterError(t *testing.T) {
+	for _, expr := range []string{
+		"", "ext", "ext =", "size > big", "color = red", "name < a",
+		"(ext = jpg", "ext in jpg", "type = file", `name ~ "("`, "ext = jpg and",
+	} {
+		if _, err := renfls.ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) error = nil\n", expr)
+		}
+	}
+}
+
+func TestFilterFunc(t *testing.T) {
+	createAlls("root", []string{"dir/text.txt", "dir/image.jpg"})
+
+	filter := renfls.And(
+		renfls.FilterFunc(func(file renfls.File) bool { return strings.HasPrefix(file.Path, "dir/") }),
+		renfls.Not(renfls.NeedRename(func(info os.FileInfo) bool { return info.Name() == "text.txt" })),
+	)
+	if err := renfls.RenameFilter("root", ".", "new", filter); err != nil {
+		t.Errorf("RenameFilter() error: %s\n", err)
+	}
+	if !isFileExist("new.jpg") || !isFileExist("root/dir/text.txt") {
+		t.Errorf("RenameFilter() didn't rename only the matching file\n")
+	}
+
+	clearTestDir()
+}
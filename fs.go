@@ -0,0 +1,221 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// fileSystem is the set of file operations used to rename files.
+type fileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	Open(name string) (*os.File, error)
+	ReadDir(dirname string) ([]os.FileInfo, error)
+	Mkdir(name string, perm os.FileMode) error
+	Rename(oldpath, newpath string) error
+	// Copy copies oldpath to newpath with its mode bits and
+	// modification time.
+	Copy(oldpath, newpath string) error
+	// Link makes newname a hard link to oldname.
+	Link(oldname, newname string) error
+	// Symlink makes newname a symbolic link to the absolute path of oldname.
+	Symlink(oldname, newname string) error
+	// Reflink makes newpath a copy-on-write clone of oldpath.
+	Reflink(oldpath, newpath string) error
+	// Relink replaces newname with a hard link to oldname
+	// that has the same content.
+	Relink(oldname, newname string) error
+	Remove(name string) error
+	RemoveAll(path string) error
+}
+
+// osFS is the file system of the operating system.
+type osFS struct {
+	// verify compares the checksums of files moved across file systems
+	// before removing the originals.
+	verify bool
+}
+
+func (osFS) Stat(name string) (os.FileInfo, error)         { return os.Stat(name) }
+func (osFS) Lstat(name string) (os.FileInfo, error)        { return os.Lstat(name) }
+func (osFS) Open(name string) (*os.File, error)            { return os.Open(name) }
+func (osFS) ReadDir(dirname string) ([]os.FileInfo, error) { return ioutil.ReadDir(dirname) }
+func (osFS) Mkdir(name string, perm os.FileMode) error     { return os.Mkdir(name, perm) }
+func (fs osFS) Rename(oldpath, newpath string) error {
+	e := os.Rename(oldpath, newpath)
+	if !errors.Is(e, syscall.EXDEV) {
+		return e
+	}
+	// Only files and symbolic links can be moved across file systems.
+	info, e1 := os.Lstat(oldpath)
+	if e1 != nil || !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+		return e
+	}
+	return fs.moveAcross(oldpath, newpath, info)
+}
+func (osFS) Copy(oldpath, newpath string) error {
+	return replace(newpath, func(tmp string) error { return copyFile(oldpath, tmp) })
+}
+func (osFS) Link(oldname, newname string) error {
+	return replace(newname, func(tmp string) error { return os.Link(oldname, tmp) })
+}
+func (osFS) Symlink(oldname, newname string) error {
+	target, e := filepath.Abs(oldname)
+	if e != nil {
+		return e
+	}
+	return replace(newname, func(tmp string) error { return os.Symlink(target, tmp) })
+}
+func (osFS) Reflink(oldpath, newpath string) error {
+	return replace(newpath, func(tmp string) error {
+		return cloneFile(oldpath, tmp, reflink)
+	})
+}
+func (osFS) Relink(oldname, newname string) error {
+	return replace(newname, func(tmp string) error { return os.Link(oldname, tmp) })
+}
+func (osFS) Remove(name string) error    { return os.Remove(name) }
+func (osFS) RemoveAll(path string) error { return os.RemoveAll(path) }
+
+// moveAcross moves a file to another file system by copying it
+// and removing the original.
+func (fs osFS) moveAcross(oldpath, newpath string, info os.FileInfo) error {
+	create := func(tmp string) error {
+		if e := copyFile(oldpath, tmp); e != nil {
+			return e
+		}
+		if fs.verify {
+			return verifyCopy(oldpath, tmp)
+		}
+		return nil
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, e := os.Readlink(oldpath)
+		if e != nil {
+			return e
+		}
+		create = func(tmp string) error { return os.Symlink(target, tmp) }
+	}
+
+	if e := replace(newpath, create); e != nil {
+		return e
+	}
+	return os.Remove(oldpath)
+}
+
+// verifyCopy returns ErrChecksumMismatch
+// if a copy doesn't have the same checksum as the original.
+func verifyCopy(oldpath, newpath string) error {
+	sum1, e := checksum(oldpath)
+	if e != nil {
+		return e
+	}
+	sum2, e := checksum(newpath)
+	if e != nil {
+		return e
+	}
+	if string(sum1) != string(sum2) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+func checksum(path string) ([]byte, error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, e := io.Copy(h, f); e != nil {
+		return nil, e
+	}
+	return h.Sum(nil), nil
+}
+
+// replace makes a file at a temporary path next to newname by create
+// and renames it to newname, so that an existing file is replaced at once.
+func replace(newname string, create func(tmp string) error) error {
+	tmp := newname + ".renfls-tmp"
+	if e := create(tmp); e != nil {
+		os.Remove(tmp)
+		return e
+	}
+	if e := os.Rename(tmp, newname); e != nil {
+		os.Remove(tmp)
+		return e
+	}
+	return nil
+}
+
+// copyFile copies the content of oldpath to a new file newpath.
+func copyFile(oldpath, newpath string) error {
+	return cloneFile(oldpath, newpath, func(dst, src *os.File) error {
+		_, e := io.Copy(dst, src)
+		return e
+	})
+}
+
+// cloneFile makes a new file newpath with the content written by clone,
+// and the mode bits and the modification time of oldpath.
+func cloneFile(oldpath, newpath string, clone func(dst, src *os.File) error) error {
+	src, e := os.Open(oldpath)
+	if e != nil {
+		return e
+	}
+	defer src.Close()
+	info, e := src.Stat()
+	if e != nil {
+		return e
+	}
+
+	dst, e := os.OpenFile(newpath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if e != nil {
+		return e
+	}
+	if e := clone(dst, src); e != nil {
+		dst.Close()
+		return e
+	}
+	if e := dst.Sync(); e != nil {
+		dst.Close()
+		return e
+	}
+	if e := dst.Close(); e != nil {
+		return e
+	}
+
+	mode := info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	if e := os.Chmod(newpath, mode); e != nil {
+		return e
+	}
+	return os.Chtimes(newpath, info.ModTime(), info.ModTime())
+}
+
+// mkdirAll creates a directory with its parents on a file system.
+func mkdirAll(fs fileSystem, path string) error {
+	if info, e := fs.Stat(path); e == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+	}
+
+	if dir := filepath.Dir(filepath.Clean(path)); dir != path {
+		if e := mkdirAll(fs, dir); e != nil {
+			return e
+		}
+	}
+	if e := fs.Mkdir(path, os.ModePerm); e != nil && !os.IsExist(e) {
+		return e
+	}
+	return nil
+}
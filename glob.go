@@ -0,0 +1,52 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"path"
+	"strings"
+)
+
+// checkGlob returns path.ErrBadPattern if a glob pattern is malformed.
+func checkGlob(pattern string) error {
+	for _, elem := range strings.Split(pattern, "/") {
+		if _, e := path.Match(elem, ""); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether a slash-separated path relative to a root
+// matches a glob pattern.
+// A pattern without slashes matches the name of the file,
+// and "**" in a pattern matches zero or more directories.
+func matchGlob(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchElems(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchElems(patterns, elems []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchElems(patterns[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], elems[0]); !ok {
+			return false
+		}
+		patterns, elems = patterns[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
@@ -0,0 +1,44 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestGlobs(t *testing.T) {
+	createAlls("root", []string{
+		"2017/raw/a.CR2", "raw/b.CR2", "2017/jpg/c.CR2", "IMG_0001.jpg", "dir/IMG_01.jpg",
+	})
+
+	report := &renfls.Report{}
+	opts := renfls.Options{Report: report}
+	condition := renfls.Condition{Globs: []string{"**/raw/*.CR2", "IMG_????.jpg"}}
+	if _, err := opts.Plan("root", ".", "new", condition); err != nil {
+		t.Errorf("Plan() error: %s\n", err)
+	}
+
+	want := map[string]string{
+		"root/2017/jpg/c.CR2": "no match",
+		"root/2017/raw/a.CR2": "glob **/raw/*.CR2",
+		"root/IMG_0001.jpg":   "glob IMG_????.jpg",
+		"root/dir/IMG_01.jpg": "no match",
+		"root/raw/b.CR2":      "glob **/raw/*.CR2",
+	}
+	if len(report.Entries) != len(want) {
+		t.Errorf("Plan() entries = %v, want %v\n", report.Entries, want)
+	}
+	for _, entry := range report.Entries {
+		if entry.Rule != want[entry.Old] {
+			t.Errorf("Plan() rule of %s = %q, want %q\n", entry.Old, entry.Rule, want[entry.Old])
+		}
+	}
+
+	if _, err := renfls.Plan("root", ".", "new", renfls.Condition{Globs: []string{"[a"}}); err == nil {
+		t.Errorf("Plan() with a bad pattern error = nil\n")
+	}
+
+	clearTestDir()
+}
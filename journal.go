@@ -0,0 +1,280 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// JournalEntry is an operation recorded in a journal.
+type JournalEntry struct {
+	Operation
+	// Dir is true if the renamed path is a directory.
+	Dir bool `json:"dir,omitempty"`
+	// File is true if the removed path is a file, which can't be restored.
+	File bool `json:"file,omitempty"`
+}
+
+// Journal writes operations to a file to undo them later.
+type Journal struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// CreateJournal creates a journal file.
+// If the file already exists, operations are appended to it.
+func CreateJournal(path string) (*Journal, error) {
+	f, e := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if e != nil {
+		return nil, e
+	}
+	return &Journal{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+func (j *Journal) record(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if e := j.enc.Encode(entry); e != nil {
+		return e
+	}
+	return j.file.Sync()
+}
+
+// ReadJournal reads the entries of a journal file in order.
+func ReadJournal(path string) ([]JournalEntry, error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if e := json.Unmarshal(scanner.Bytes(), &entry); e != nil {
+			return nil, fmt.Errorf("ReadJournal %s: %s", path, e)
+		}
+		entries = append(entries, entry)
+	}
+	if e := scanner.Err(); e != nil {
+		return nil, e
+	}
+	return entries, nil
+}
+
+// journalFS is a file system that records operations.
+type journalFS struct {
+	fileSystem
+	record func(entry JournalEntry) error
+}
+
+func (fs *journalFS) Mkdir(name string, perm os.FileMode) error {
+	if e := fs.fileSystem.Mkdir(name, perm); e != nil {
+		return e
+	}
+	return fs.record(JournalEntry{Operation: Operation{Op: OpMkdir, Old: name}})
+}
+
+func (fs *journalFS) Rename(oldpath, newpath string) error {
+	info, e := fs.fileSystem.Lstat(oldpath)
+	if e != nil {
+		return e
+	}
+	if e := fs.fileSystem.Rename(oldpath, newpath); e != nil {
+		return e
+	}
+	return fs.record(JournalEntry{
+		Operation: Operation{Op: OpRename, Old: oldpath, New: newpath},
+		Dir:       info.IsDir(),
+	})
+}
+
+func (fs *journalFS) Copy(oldpath, newpath string) error {
+	return fs.duplicate(OpCopy, fs.fileSystem.Copy, oldpath, newpath)
+}
+
+func (fs *journalFS) Link(oldname, newname string) error {
+	return fs.duplicate(OpLink, fs.fileSystem.Link, oldname, newname)
+}
+
+func (fs *journalFS) Symlink(oldname, newname string) error {
+	return fs.duplicate(OpSymlink, fs.fileSystem.Symlink, oldname, newname)
+}
+
+func (fs *journalFS) Reflink(oldpath, newpath string) error {
+	return fs.duplicate(OpReflink, fs.fileSystem.Reflink, oldpath, newpath)
+}
+
+func (fs *journalFS) Relink(oldname, newname string) error {
+	return fs.duplicate(OpRelink, fs.fileSystem.Relink, oldname, newname)
+}
+
+func (fs *journalFS) duplicate(op string, duplicate func(string, string) error, oldpath, newpath string) error {
+	if e := duplicate(oldpath, newpath); e != nil {
+		return e
+	}
+	return fs.record(JournalEntry{Operation: Operation{Op: op, Old: oldpath, New: newpath}})
+}
+
+func (fs *journalFS) Remove(name string) error {
+	info, e := fs.fileSystem.Lstat(name)
+	if e != nil {
+		return e
+	}
+	if e := fs.fileSystem.Remove(name); e != nil {
+		return e
+	}
+	return fs.record(JournalEntry{Operation: Operation{Op: OpRemove, Old: name}, File: !info.IsDir()})
+}
+
+func (fs *journalFS) RemoveAll(path string) error {
+	// Every path removed is recorded,
+	// so that undoing restores the directories under the path.
+	entries, e := fs.removeEntries(path)
+	if e != nil {
+		return e
+	}
+	if e := fs.fileSystem.RemoveAll(path); e != nil {
+		return e
+	}
+	for _, entry := range entries {
+		if e := fs.record(entry); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// removeEntries returns the entries to remove a path and the paths under it.
+func (fs *journalFS) removeEntries(path string) ([]JournalEntry, error) {
+	info, e := fs.fileSystem.Lstat(path)
+	if os.IsNotExist(e) {
+		return nil, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+	entries := []JournalEntry{{Operation: Operation{Op: OpRemove, Old: path}, File: !info.IsDir()}}
+	if !info.IsDir() {
+		return entries, nil
+	}
+
+	infos, e := fs.fileSystem.ReadDir(path)
+	if e != nil {
+		return nil, e
+	}
+	for _, info := range infos {
+		sub, e := fs.removeEntries(filepath.Join(path, info.Name()))
+		if e != nil {
+			return nil, e
+		}
+		entries = append(entries, sub...)
+	}
+	return entries, nil
+}
+
+// UndoError is the error of entries that can no longer be reverted.
+type UndoError struct {
+	Failures []UndoFailure
+}
+
+// UndoFailure is an entry that can no longer be reverted.
+type UndoFailure struct {
+	Entry JournalEntry
+	Err   error
+}
+
+func (e *UndoError) Error() string {
+	return fmt.Sprintf("Undo: %d entries can't be reverted%s",
+		len(e.Failures), failuresString(e.Failures))
+}
+
+func failuresString(failures []UndoFailure) string {
+	var s string
+	for _, f := range failures {
+		s += fmt.Sprintf("\n%s: %s", f.Entry, f.Err)
+	}
+	return s
+}
+
+// Undo reverts the operations recorded in a journal file in reverse order.
+// Entries that can no longer be reverted are reported as *UndoError.
+func Undo(journalPath string) error {
+	entries, e := ReadJournal(journalPath)
+	if e != nil {
+		return e
+	}
+
+	if _, failures := undoAll(osFS{}, entries); len(failures) > 0 {
+		return &UndoError{failures}
+	}
+	return nil
+}
+
+// undoAll reverts entries in reverse order
+// and returns the reverted entries and the failures.
+func undoAll(fs fileSystem, entries []JournalEntry) ([]JournalEntry, []UndoFailure) {
+	var reverted []JournalEntry
+	var failures []UndoFailure
+	for i := len(entries) - 1; i >= 0; i-- {
+		if e := undo(fs, entries[i]); e != nil {
+			failures = append(failures, UndoFailure{entries[i], e})
+		} else {
+			reverted = append(reverted, entries[i])
+		}
+	}
+	return reverted, failures
+}
+
+func undo(fs fileSystem, entry JournalEntry) error {
+	switch entry.Op {
+	case OpRename:
+		if _, e := fs.Lstat(entry.Old); e == nil {
+			return fmt.Errorf("%s already exists", entry.Old)
+		}
+		// Directories removed by the operations are restored
+		// by undoing the removes before.
+		if _, e := fs.Lstat(entry.New); e != nil {
+			return e
+		}
+		dir, _ := filepath.Split(entry.Old)
+		if dir != "" {
+			if e := mkdirAll(fs, dir); e != nil {
+				return e
+			}
+		}
+		return fs.Rename(entry.New, entry.Old)
+	case OpCopy, OpLink, OpSymlink, OpReflink:
+		return fs.Remove(entry.New)
+	case OpRelink:
+		// The hard link is replaced with a copy of itself,
+		// so that the files are independent again.
+		return fs.Copy(entry.New, entry.New)
+	case OpMkdir:
+		return fs.Remove(entry.Old)
+	case OpRemove:
+		if entry.File {
+			return fmt.Errorf("%s was deleted and can't be restored", entry.Old)
+		}
+		return mkdirAll(fs, entry.Old)
+	}
+	return fmt.Errorf("unknown operation %q", entry.Op)
+}
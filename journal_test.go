@@ -0,0 +1,153 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+const journalPath = "journal"
+
+func TestUndo(t *testing.T) {
+	for _, test := range []struct {
+		mockFiles []string
+		rename    func(opts renfls.Options) error
+	}{
+		{
+			[]string{"dir1/text.txt", "dir1/sub/text.txt", "dir2/text.txt"},
+			func(opts renfls.Options) error {
+				return opts.ToSubDirsName("root")
+			},
+		},
+		{
+			[]string{"dir1/text.txt", "dir1/image.jpg", "dir2/text.txt"},
+			func(opts renfls.Options) error {
+				return opts.WalkToRootSubDirName("root", "root",
+					renfls.Condition{Exts: []string{"txt"}})
+			},
+		},
+		{
+			[]string{"dir1/text.txt", "dir1/image.jpg", "text.txt"},
+			func(opts renfls.Options) error {
+				return opts.WalkRename("root", ".", "new", renfls.Condition{})
+			},
+		},
+	} {
+		createAlls("root", test.mockFiles)
+		os.Mkdir("root/empty", os.ModePerm)
+
+		journal, err := renfls.CreateJournal(journalPath)
+		if err != nil {
+			t.Fatalf("CreateJournal() error: %s\n", err)
+		}
+		if err := test.rename(renfls.Options{Journal: journal}); err != nil {
+			t.Errorf("rename(%v) error: %s\n", test.mockFiles, err)
+		}
+		journal.Close()
+
+		if err := renfls.Undo(journalPath); err != nil {
+			t.Errorf("Undo(%v) error: %s\n", test.mockFiles, err)
+		}
+
+		for _, path := range test.mockFiles {
+			if !isFileExist(filepath.Join("root", path)) {
+				t.Errorf("The path %q didn't be restored.\n", path)
+			}
+		}
+		if !isExist("root/empty") {
+			t.Errorf("The empty directory didn't be restored.\n")
+		}
+		for _, dir := range []string{"root/ignore", "root/fail"} {
+			if isExist(dir) {
+				t.Errorf("The directory %q is not removed.\n", dir)
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestUndoFailure(t *testing.T) {
+	createAlls("root", []string{"dir/text.txt", "dir/image.jpg"})
+
+	journal, err := renfls.CreateJournal(journalPath)
+	if err != nil {
+		t.Fatalf("CreateJournal() error: %s\n", err)
+	}
+	opts := renfls.Options{Journal: journal}
+	if err := opts.WalkRename("root", ".", "new", renfls.Condition{}); err != nil {
+		t.Errorf("WalkRename() error: %s\n", err)
+	}
+	journal.Close()
+
+	os.Remove("new.txt")
+
+	err = renfls.Undo(journalPath)
+	undoErr, ok := err.(*renfls.UndoError)
+	if !ok {
+		t.Fatalf("Undo() = %v, want *UndoError\n", err)
+	}
+	if len(undoErr.Failures) != 1 || undoErr.Failures[0].Entry.New != "new.txt" {
+		t.Errorf("Undo() failures = %v, want new.txt\n", undoErr.Failures)
+	}
+	if !isFileExist("root/dir/image.jpg") {
+		t.Errorf("The path %q didn't be restored.\n", "root/dir/image.jpg")
+	}
+
+	clearTestDir()
+}
+
+func TestUndoRemovedDir(t *testing.T) {
+	createAlls("root", []string{"album/a.txt"})
+	defer clearTestDir()
+
+	journal, err := renfls.CreateJournal(journalPath)
+	if err != nil {
+		t.Fatalf("CreateJournal() error: %s\n", err)
+	}
+	opts := renfls.Options{Journal: journal, Dirs: true, Name: "x_{name}"}
+	if err := opts.WalkRename("root", "root", "", renfls.Condition{}); err != nil {
+		t.Errorf("WalkRename() error: %s\n", err)
+	}
+	journal.Close()
+
+	// The renamed directory removed by others can't be restored.
+	os.RemoveAll("root/x_album")
+	if _, ok := renfls.Undo(journalPath).(*renfls.UndoError); !ok {
+		t.Errorf("Undo() error is not *UndoError\n")
+	}
+	if isExist("root/album") {
+		t.Errorf("Undo() made the directory root/album without its files\n")
+	}
+}
+
+func TestUndoOverwrite(t *testing.T) {
+	createAlls(".", []string{"root/d.txt", "dest/d.txt"})
+	defer clearTestDir()
+	ioutil.WriteFile("root/d.txt", []byte("new"), 0666)
+	ioutil.WriteFile("dest/d.txt", []byte("old"), 0666)
+
+	journal, err := renfls.CreateJournal(journalPath)
+	if err != nil {
+		t.Fatalf("CreateJournal() error: %s\n", err)
+	}
+	opts := renfls.Options{Journal: journal, Collision: renfls.CollisionPolicy{Strategy: renfls.CollisionOverwrite}}
+	if _, err := opts.Rename("root/d.txt", "dest", "d"); err != nil {
+		t.Errorf("Rename() error: %s\n", err)
+	}
+	journal.Close()
+
+	if err := renfls.Undo(journalPath); err != nil {
+		t.Errorf("Undo() error: %s\n", err)
+	}
+	for path, want := range map[string]string{"root/d.txt": "new", "dest/d.txt": "old"} {
+		if b, _ := ioutil.ReadFile(path); string(b) != want {
+			t.Errorf("Undo(): %s has %q, want %q\n", path, b, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Junk is an action for junk files like ".DS_Store" in walks.
+type Junk int
+
+// Actions for junk files.
+const (
+	// JunkIgnore leaves junk files as they are.
+	JunkIgnore Junk = iota
+	// JunkDelete deletes junk files that match conditions.
+	// Junk files are left as they are unless Options.Mode is ModeMove.
+	// If the operations are journaled or transactional, junk files are
+	// moved to backups like overwritten files, so that undoing them
+	// restores the junk files.
+	JunkDelete
+)
+
+var junkNames = []string{"ignore", "delete"}
+
+func (j Junk) String() string {
+	if j < 0 || int(j) >= len(junkNames) {
+		return fmt.Sprintf("Junk(%d)", int(j))
+	}
+	return junkNames[j]
+}
+
+// ParseJunk returns the action of a name like "delete".
+func ParseJunk(name string) (Junk, error) {
+	for i, n := range junkNames {
+		if n == name {
+			return Junk(i), nil
+		}
+	}
+	return 0, fmt.Errorf("ParseJunk %q: unknown action, want one of %s",
+		name, strings.Join(junkNames, ", "))
+}
+
+// DefaultJunkFiles is the patterns of junk files
+// when Options.JunkFiles is nil.
+var DefaultJunkFiles = []string{".DS_Store", "._*", "Thumbs.db", "ehthumbs.db", "desktop.ini", ".directory"}
+
+// isHidden returns whether a file name begins with a dot.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// junkPattern returns the pattern of junk files that matches a file name
+// case-insensitively, or an empty string.
+func (r *renamer) junkPattern(name string) string {
+	patterns := r.opts.JunkFiles
+	if patterns == nil {
+		patterns = DefaultJunkFiles
+	}
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), name); ok {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// junk ignores or deletes a junk file by the options.
+func (r *renamer) junk(path, pattern string) error {
+	rule := "junk " + pattern
+	if r.opts.Junk != JunkDelete || r.opts.Mode != ModeMove {
+		r.record(ReportEntry{Old: path, Rule: rule, Status: StatusIgnored})
+		return nil
+	}
+	remove := r.fs.Remove
+	if r.opts.Journal != nil || r.opts.Transactional {
+		remove = r.backup
+	}
+	if e := remove(path); e != nil {
+		r.record(ReportEntry{Old: path, Rule: rule, Status: StatusFailed, Err: e})
+		return r.fail(e)
+	}
+	r.record(ReportEntry{Old: path, Rule: rule, Status: StatusDeleted})
+	return nil
+}
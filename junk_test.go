@@ -0,0 +1,122 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestHidden(t *testing.T) {
+	for _, test := range []struct {
+		hidden bool
+		want   map[string]bool
+	}{
+		{false, map[string]bool{
+			"root/dir.txt": true, "root/dir-2": false, "root/dir.json": false,
+			"root/dir/.env": true, "root/dir/.cache/a.txt": true,
+		}},
+		{true, map[string]bool{
+			"root/dir.txt": true, "root/dir-2": true, "root/dir-2.txt": true, "root/dir.json": true,
+			"root/dir/.env": false, "root/dir/.cache/a.txt": false,
+		}},
+	} {
+		createAlls("root", []string{"dir/text.txt", "dir/.env", "dir/.eslintrc.json", "dir/.cache/a.txt"})
+
+		opts := renfls.Options{Hidden: test.hidden}
+		if err := opts.WalkToRootDirName("root/dir", "root", renfls.Condition{}); err != nil {
+			t.Errorf("WalkToRootDirName(hidden %v) error: %s\n", test.hidden, err)
+		}
+		for path, want := range test.want {
+			if isExist(path) != want {
+				t.Errorf("WalkToRootDirName(hidden %v): %s exists = %v, want %v\n", test.hidden, path, !want, want)
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestJunk(t *testing.T) {
+	for _, test := range []struct {
+		junk    renfls.Junk
+		mode    renfls.Mode
+		deleted int
+		exist   bool
+	}{
+		{renfls.JunkIgnore, renfls.ModeMove, 0, true},
+		{renfls.JunkDelete, renfls.ModeMove, 3, false},
+		{renfls.JunkDelete, renfls.ModeCopy, 0, true},
+	} {
+		createAlls("root", []string{"dir/text.txt", "dir/.DS_Store", "dir/thumbs.db", "dir/desktop.ini"})
+
+		report := &renfls.Report{}
+		opts := renfls.Options{Junk: test.junk, Mode: test.mode, Report: report}
+		if err := opts.WalkToRootDirName("root/dir", "root", renfls.Condition{}); err != nil {
+			t.Errorf("WalkToRootDirName(%s, %s) error: %s\n", test.junk, test.mode, err)
+		}
+		if report.Renamed != 1 || report.Deleted != test.deleted {
+			t.Errorf("WalkToRootDirName(%s, %s): %d renamed and %d deleted, want 1 and %d\n",
+				test.junk, test.mode, report.Renamed, report.Deleted, test.deleted)
+		}
+		for _, path := range []string{"root/dir/.DS_Store", "root/dir/thumbs.db", "root/dir/desktop.ini"} {
+			if isExist(path) != test.exist {
+				t.Errorf("WalkToRootDirName(%s, %s): %s exists = %v\n", test.junk, test.mode, path, !test.exist)
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestJunkUndo(t *testing.T) {
+	// The new name of the last file is too long to be renamed.
+	name := strings.Repeat("d", 200)
+	createAlls("root", []string{"dir/.DS_Store", "dir/a.txt", "dir/b." + strings.Repeat("x", 60)})
+	defer clearTestDir()
+
+	opts := renfls.Options{Junk: renfls.JunkDelete, Transactional: true}
+	err := opts.WalkRename("root", "root", name, renfls.Condition{})
+	if rollbackErr, ok := err.(*renfls.RollbackError); !ok || len(rollbackErr.Failures) != 0 {
+		t.Errorf("WalkRename() = %v, want *RollbackError without failures\n", err)
+	}
+	if !isFileExist("root/dir/.DS_Store") || isExist("root/dir/.DS_Store.renfls-bak") {
+		t.Errorf("WalkRename(): the junk file didn't be rolled back\n")
+	}
+
+	journal, err := renfls.CreateJournal(journalPath)
+	if err != nil {
+		t.Fatalf("CreateJournal() error: %s\n", err)
+	}
+	opts = renfls.Options{Junk: renfls.JunkDelete, Journal: journal}
+	if err := opts.WalkRename("root", "root", "new", renfls.Condition{}); err != nil {
+		t.Errorf("WalkRename() error: %s\n", err)
+	}
+	journal.Close()
+	if isExist("root/dir/.DS_Store") {
+		t.Errorf("WalkRename(): the junk file didn't be deleted\n")
+	}
+	if err := renfls.Undo(journalPath); err != nil {
+		t.Errorf("Undo() error: %s\n", err)
+	}
+	if !isFileExist("root/dir/.DS_Store") {
+		t.Errorf("Undo(): the junk file didn't be restored\n")
+	}
+}
+
+func TestJunkExclude(t *testing.T) {
+	createAlls("root", []string{"keep/.DS_Store", "dir/.DS_Store", "dir/text.txt"})
+	defer clearTestDir()
+
+	// Junk files are deleted only if they are selected.
+	opts := renfls.Options{Junk: renfls.JunkDelete, MinDepth: 1}
+	condition := renfls.Condition{Exclude: []renfls.Rule{{Globs: []string{"keep/**"}}}}
+	if err := opts.WalkRename("root", "root", "new", condition); err != nil {
+		t.Errorf("WalkRename() error: %s\n", err)
+	}
+	if !isFileExist("root/keep/.DS_Store") || isExist("root/dir/.DS_Store") {
+		t.Errorf("WalkRename(): the excluded junk file didn't be left\n")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FileType is a type of files selected by Condition.Types.
+type FileType int
+
+// Types of files.
+const (
+	// TypeRegular is regular files.
+	TypeRegular FileType = iota
+	// TypeSymlink is symbolic links.
+	TypeSymlink
+	// TypeEmpty is empty regular files.
+	TypeEmpty
+	// TypeDir is directories.
+	TypeDir
+	// TypeOther is the files of the other types like devices.
+	TypeOther
+)
+
+var fileTypeNames = []string{"regular", "symlink", "empty", "dir", "other"}
+
+func (t FileType) String() string {
+	if t < 0 || int(t) >= len(fileTypeNames) {
+		return fmt.Sprintf("FileType(%d)", int(t))
+	}
+	return fileTypeNames[t]
+}
+
+// FileTypeNames returns the names of all the types parsed by ParseFileType.
+func FileTypeNames() []string {
+	return append([]string(nil), fileTypeNames...)
+}
+
+// ParseFileType returns the type of a name like "symlink".
+func ParseFileType(name string) (FileType, error) {
+	for i, n := range fileTypeNames {
+		if n == name {
+			return FileType(i), nil
+		}
+	}
+	return 0, fmt.Errorf("ParseFileType %q: unknown type, want one of %s",
+		name, strings.Join(fileTypeNames, ", "))
+}
+
+func (t FileType) match(info os.FileInfo) bool {
+	switch t {
+	case TypeRegular:
+		return info.Mode().IsRegular()
+	case TypeSymlink:
+		return info.Mode()&os.ModeSymlink != 0
+	case TypeEmpty:
+		return info.Mode().IsRegular() && info.Size() == 0
+	case TypeDir:
+		return info.IsDir()
+	case TypeOther:
+		return fileTypeOf(info) == TypeOther
+	}
+	return false
+}
+
+// fileTypeOf returns the type of a file except TypeEmpty.
+func fileTypeOf(info os.FileInfo) FileType {
+	switch mode := info.Mode(); {
+	case mode.IsRegular():
+		return TypeRegular
+	case mode.IsDir():
+		return TypeDir
+	case mode&os.ModeSymlink != 0:
+		return TypeSymlink
+	}
+	return TypeOther
+}
+
+// matchMetadata returns whether a file has the size, the modification time
+// and the type of the condition, and the rule that excluded it.
+func (condition Condition) matchMetadata(info os.FileInfo) (bool, string) {
+	if condition.MinSize > 0 && info.Size() < condition.MinSize {
+		return false, fmt.Sprintf("smaller than %d bytes", condition.MinSize)
+	}
+	if condition.MaxSize > 0 && info.Size() > condition.MaxSize {
+		return false, fmt.Sprintf("larger than %d bytes", condition.MaxSize)
+	}
+	if !condition.ModifiedAfter.IsZero() && !info.ModTime().After(condition.ModifiedAfter) {
+		return false, "modified until " + condition.ModifiedAfter.Format(timeLayouts[0])
+	}
+	if !condition.ModifiedBefore.IsZero() && !info.ModTime().Before(condition.ModifiedBefore) {
+		return false, "modified since " + condition.ModifiedBefore.Format(timeLayouts[0])
+	}
+	if len(condition.Types) > 0 {
+		names := make([]string, len(condition.Types))
+		for i, t := range condition.Types {
+			if t.match(info) {
+				return true, ""
+			}
+			names[i] = t.String()
+		}
+		return false, "not " + strings.Join(names, " or ")
+	}
+	return true, ""
+}
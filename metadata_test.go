@@ -0,0 +1,84 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestConditionMetadata(t *testing.T) {
+	now := time.Now()
+	writeFile("root/dir/large-new.mp4", strings.Repeat("a", 2048))
+	writeFile("root/dir/large-old.mp4", strings.Repeat("a", 2048))
+	writeFile("root/dir/small-new.mp4", "a")
+	writeFile("root/dir/empty.mp4", "")
+	os.Symlink("large-new.mp4", "root/dir/link.mp4")
+	os.Chtimes("root/dir/large-old.mp4", now.AddDate(-1, 0, 0), now.AddDate(-1, 0, 0))
+
+	for _, test := range []struct {
+		condition renfls.Condition
+		want      []string
+	}{
+		{
+			renfls.Condition{Exts: []string{"mp4"}, MinSize: 1024, ModifiedAfter: now.AddDate(0, -1, 0)},
+			[]string{"root/dir/large-new.mp4"},
+		},
+		{
+			renfls.Condition{MaxSize: 1024, Types: []renfls.FileType{renfls.TypeRegular}},
+			[]string{"root/dir/empty.mp4", "root/dir/small-new.mp4"},
+		},
+		{
+			renfls.Condition{ModifiedBefore: now.AddDate(0, -1, 0)},
+			[]string{"root/dir/large-old.mp4"},
+		},
+		{
+			renfls.Condition{Types: []renfls.FileType{renfls.TypeSymlink, renfls.TypeEmpty}},
+			[]string{"root/dir/empty.mp4", "root/dir/link.mp4"},
+		},
+	} {
+		report := &renfls.Report{}
+		opts := renfls.Options{Report: report}
+		if _, err := opts.Plan("root", ".", "new", test.condition); err != nil {
+			t.Errorf("Plan(%+v) error: %s\n", test.condition, err)
+		}
+		var renamed []string
+		for _, entry := range report.Entries {
+			if entry.Status == renfls.StatusRenamed {
+				renamed = append(renamed, entry.Old)
+			}
+		}
+		if strings.Join(renamed, ",") != strings.Join(test.want, ",") {
+			t.Errorf("Plan(%+v) renamed %v, want %v\n", test.condition, renamed, test.want)
+		}
+	}
+
+	clearTestDir()
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"100": 100, "100k": 100 << 10, "10MB": 10 << 20, "1G": 1 << 30} {
+		if got, err := renfls.ParseSize(s); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d\n", s, got, err, want)
+		}
+	}
+	if _, err := renfls.ParseSize("big"); err == nil {
+		t.Errorf("ParseSize(%q) error = nil\n", "big")
+	}
+}
+
+func TestParseFileType(t *testing.T) {
+	for _, name := range renfls.FileTypeNames() {
+		fileType, err := renfls.ParseFileType(name)
+		if err != nil || fileType.String() != name {
+			t.Errorf("ParseFileType(%q) = %v, %v\n", name, fileType, err)
+		}
+	}
+	if _, err := renfls.ParseFileType("file"); err == nil {
+		t.Errorf("ParseFileType(%q) error = nil\n", "file")
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode is a way to put files at new paths.
+type Mode int
+
+// Ways to put files at new paths.
+const (
+	// ModeMove moves files to new paths.
+	ModeMove Mode = iota
+	// ModeCopy copies files to new paths with their mode bits and
+	// modification times, and leaves the files as they are.
+	ModeCopy
+	// ModeHardlink makes hard links to files at new paths.
+	ModeHardlink
+	// ModeSymlink makes symbolic links to the absolute paths of files
+	// at new paths.
+	ModeSymlink
+	// ModeReflink makes copy-on-write clones of files at new paths
+	// on file systems that support it.
+	ModeReflink
+)
+
+var modeNames = []string{"move", "copy", "hardlink", "symlink", "reflink"}
+
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+	return modeNames[m]
+}
+
+// ParseMode returns the mode of a name like "copy".
+func ParseMode(name string) (Mode, error) {
+	for i, n := range modeNames {
+		if n == name {
+			return Mode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("ParseMode %q: unknown mode, want one of %s",
+		name, strings.Join(modeNames, ", "))
+}
+
+// place puts a file at a new path by the mode of the options.
+func (r *renamer) place(oldPath, newPath string) (string, error) {
+	var op string
+	var e error
+	switch r.opts.Mode {
+	case ModeMove:
+		return r.move(oldPath, newPath)
+	case ModeCopy:
+		op, e = "Copy", r.fs.Copy(oldPath, newPath)
+	case ModeHardlink:
+		op, e = "Link", r.fs.Link(oldPath, newPath)
+	case ModeSymlink:
+		op, e = "Symlink", r.fs.Symlink(oldPath, newPath)
+	case ModeReflink:
+		op, e = "Reflink", r.fs.Reflink(oldPath, newPath)
+	default:
+		return "", fmt.Errorf("Rename %s: unknown mode %s", oldPath, r.opts.Mode)
+	}
+	if e != nil {
+		return "", &RenameError{op, oldPath, newPath, underlying(e)}
+	}
+	if sum, ok := r.hashes[oldPath]; ok {
+		r.hashes[newPath] = sum
+	} else {
+		delete(r.hashes, newPath)
+	}
+	return newPath, nil
+}
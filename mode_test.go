@@ -0,0 +1,127 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestMode(t *testing.T) {
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, mode := range []renfls.Mode{renfls.ModeCopy, renfls.ModeHardlink, renfls.ModeSymlink} {
+		writeFile("root/dir1/text.txt", "a")
+		os.Chmod("root/dir1/text.txt", 0640)
+		os.Chtimes("root/dir1/text.txt", mtime, mtime)
+
+		opts := renfls.Options{Mode: mode}
+		if err := opts.WalkToRootSubDirName("root", "root", renfls.Condition{}); err != nil {
+			t.Errorf("WalkToRootSubDirName(%v) error: %s\n", mode, err)
+		}
+
+		if content := readFile("root/dir1/text.txt"); content != "a" {
+			t.Errorf("WalkToRootSubDirName(%v): the old file has %q\n", mode, content)
+		}
+		if content := readFile("root/dir1.txt"); content != "a" {
+			t.Errorf("WalkToRootSubDirName(%v): the new file has %q\n", mode, content)
+		}
+		oldInfo, _ := os.Stat("root/dir1/text.txt")
+		info, err := os.Lstat("root/dir1.txt")
+		if err != nil {
+			t.Fatalf("WalkToRootSubDirName(%v): %s\n", mode, err)
+		}
+		switch mode {
+		case renfls.ModeCopy:
+			if os.SameFile(oldInfo, info) || info.Mode() != 0640 || !info.ModTime().Equal(mtime) {
+				t.Errorf("WalkToRootSubDirName(%v): the copy has mode %v and mtime %v\n",
+					mode, info.Mode(), info.ModTime())
+			}
+		case renfls.ModeHardlink:
+			if !os.SameFile(oldInfo, info) {
+				t.Errorf("WalkToRootSubDirName(%v): the new file is not a hard link\n", mode)
+			}
+		case renfls.ModeSymlink:
+			if info.Mode()&os.ModeSymlink == 0 {
+				t.Errorf("WalkToRootSubDirName(%v): the new file is not a symbolic link\n", mode)
+			}
+		}
+		if isExist("root/ignore") {
+			t.Errorf("WalkToRootSubDirName(%v): the directories are moved\n", mode)
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestPlanCopy(t *testing.T) {
+	createAlls("root", []string{"dir1/text.txt", "dir2/image.jpg"})
+
+	opts := renfls.Options{Mode: renfls.ModeCopy}
+	operations, err := opts.PlanToRootSubDirName("root", "root", renfls.Condition{})
+	if err != nil {
+		t.Errorf("PlanToRootSubDirName() error: %s\n", err)
+	}
+	want := []renfls.Operation{
+		{Op: renfls.OpCopy, Old: "root/dir1/text.txt", New: "root/dir1.txt"},
+		{Op: renfls.OpCopy, Old: "root/dir2/image.jpg", New: "root/dir2.jpg"},
+	}
+	if !reflect.DeepEqual(operations, want) {
+		t.Errorf("PlanToRootSubDirName() = %v, want %v\n", operations, want)
+	}
+
+	clearTestDir()
+}
+
+func TestUndoCopy(t *testing.T) {
+	createAlls("root", []string{"dir1/text.txt"})
+
+	journal, _ := renfls.CreateJournal("renfls.journal")
+	opts := renfls.Options{Mode: renfls.ModeCopy, Journal: journal}
+	if err := opts.WalkToRootSubDirName("root", "root", renfls.Condition{}); err != nil {
+		t.Errorf("WalkToRootSubDirName() error: %s\n", err)
+	}
+	journal.Close()
+
+	if err := renfls.Undo("renfls.journal"); err != nil {
+		t.Errorf("Undo() error: %s\n", err)
+	}
+	if isExist("root/dir1.txt") || !isFileExist("root/dir1/text.txt") {
+		t.Errorf("Undo() didn't remove the copy\n")
+	}
+
+	clearTestDir()
+}
+
+func TestRenameAcrossFileSystems(t *testing.T) {
+	// /dev/shm is usually on another file system than the test data.
+	dest, err := ioutil.TempDir("/dev/shm", "renfls")
+	if err != nil {
+		t.Skip("No other file system: ", err)
+	}
+	defer os.RemoveAll(dest)
+
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeFile("root/dir/text.txt", "a")
+	os.Chmod("root/dir/text.txt", 0640)
+	os.Chtimes("root/dir/text.txt", mtime, mtime)
+
+	opts := renfls.Options{Verify: true}
+	newPath, err := opts.Rename("root/dir/text.txt", dest, "new")
+	if err != nil {
+		t.Fatalf("Rename() error: %s\n", err)
+	}
+	if isExist("root/dir/text.txt") {
+		t.Errorf("Rename() didn't remove the old file\n")
+	}
+	info, err := os.Stat(newPath)
+	if err != nil || readFile(newPath) != "a" || info.Mode() != 0640 || !info.ModTime().Equal(mtime) {
+		t.Errorf("Rename() moved the file to %s with %v, %s\n", newPath, info, err)
+	}
+
+	clearTestDir()
+}
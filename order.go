@@ -0,0 +1,137 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Order is an order in which files in a directory are walked.
+// Files walked earlier get new names without suffixes first.
+type Order int
+
+// Orders of files in a directory.
+const (
+	// OrderName walks files in lexical order of names.
+	OrderName Order = iota
+	// OrderNatural walks files in order of names with numbers
+	// compared by value, like "img2" before "img10".
+	OrderNatural
+	// OrderModTime walks older files first.
+	OrderModTime
+	// OrderSize walks smaller files first.
+	OrderSize
+	// OrderExifDate walks images taken earlier first.
+	// Files without EXIF dates are ordered by the modification times.
+	OrderExifDate
+)
+
+var orderNames = []string{"name", "natural", "mtime", "size", "exif"}
+
+func (o Order) String() string {
+	if o < 0 || int(o) >= len(orderNames) {
+		return fmt.Sprintf("Order(%d)", int(o))
+	}
+	return orderNames[o]
+}
+
+// ParseOrder returns the order of a name like "mtime".
+func ParseOrder(name string) (Order, error) {
+	for i, n := range orderNames {
+		if n == name {
+			return Order(i), nil
+		}
+	}
+	return 0, fmt.Errorf("ParseOrder %q: unknown order, want one of %s",
+		name, strings.Join(orderNames, ", "))
+}
+
+// sortInfos sorts files read in a directory in the order of the options.
+// Files in the same place of the order are kept in order of names.
+func (r *renamer) sortInfos(dir string, infos []os.FileInfo) {
+	switch r.opts.Order {
+	case OrderNatural:
+		sort.SliceStable(infos, func(i, j int) bool {
+			return naturalLess(infos[i].Name(), infos[j].Name())
+		})
+	case OrderModTime:
+		sort.SliceStable(infos, func(i, j int) bool {
+			return infos[i].ModTime().Before(infos[j].ModTime())
+		})
+	case OrderSize:
+		sort.SliceStable(infos, func(i, j int) bool {
+			return infos[i].Size() < infos[j].Size()
+		})
+	case OrderExifDate:
+		dates := make(map[string]time.Time, len(infos))
+		for _, info := range infos {
+			dates[info.Name()] = r.exifDate(filepath.Join(dir, info.Name()), info)
+		}
+		sort.SliceStable(infos, func(i, j int) bool {
+			return dates[infos[i].Name()].Before(dates[infos[j].Name()])
+		})
+	}
+}
+
+// exifDate returns the date when an image was taken,
+// or the modification time if it is unknown.
+func (r *renamer) exifDate(path string, info os.FileInfo) time.Time {
+	if !info.Mode().IsRegular() {
+		return info.ModTime()
+	}
+	f, e := r.fs.Open(path)
+	if e != nil {
+		return info.ModTime()
+	}
+	defer f.Close()
+	t, e := readExifDate(f)
+	if e != nil {
+		return info.ModTime()
+	}
+	return t
+}
+
+// naturalLess reports whether a is less than b
+// comparing runs of digits by their values.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		ca, cb := chunk(a), chunk(b)
+		a, b = a[len(ca):], b[len(cb):]
+		if ca == cb {
+			continue
+		}
+		if isDigit(ca[0]) && isDigit(cb[0]) {
+			na, nb := strings.TrimLeft(ca, "0"), strings.TrimLeft(cb, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			// Fewer leading zeros come first.
+			return len(ca) < len(cb)
+		}
+		return ca < cb
+	}
+	return len(a) < len(b)
+}
+
+// chunk returns the leading run of digits or non-digits of s.
+func chunk(s string) string {
+	digit := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digit {
+		i++
+	}
+	return s[:i]
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,114 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestOrder(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		order renfls.Order
+		files map[string]string
+		want  map[string]string
+	}{
+		{
+			renfls.OrderName,
+			map[string]string{"img10.jpg": "", "img2.jpg": ""},
+			map[string]string{"root/img10.jpg": "trip.jpg", "root/img2.jpg": "trip-2.jpg"},
+		},
+		{
+			renfls.OrderNatural,
+			map[string]string{"img10.jpg": "", "img2.jpg": ""},
+			map[string]string{"root/img2.jpg": "trip.jpg", "root/img10.jpg": "trip-2.jpg"},
+		},
+		{
+			renfls.OrderModTime,
+			map[string]string{"a.jpg": "", "b.jpg": "", "c.jpg": ""},
+			map[string]string{"root/c.jpg": "trip.jpg", "root/a.jpg": "trip-2.jpg", "root/b.jpg": "trip-3.jpg"},
+		},
+		{
+			renfls.OrderSize,
+			map[string]string{"a.jpg": "aaa", "b.jpg": "b", "c.jpg": "cc"},
+			map[string]string{"root/b.jpg": "trip.jpg", "root/c.jpg": "trip-2.jpg", "root/a.jpg": "trip-3.jpg"},
+		},
+		{
+			renfls.OrderExifDate,
+			map[string]string{
+				"a.jpg": exifJPEG("2017:05:02 10:00:00"),
+				"b.jpg": exifJPEG("2017:05:01 10:00:00"),
+				"c.jpg": "",
+			},
+			map[string]string{"root/b.jpg": "trip.jpg", "root/a.jpg": "trip-2.jpg", "root/c.jpg": "trip-3.jpg"},
+		},
+	} {
+		for name, content := range test.files {
+			writeFile("root/"+name, content)
+		}
+		// c is the oldest and b is the newest.
+		os.Chtimes("root/c.jpg", now.Add(-3*time.Hour), now.Add(-3*time.Hour))
+		os.Chtimes("root/a.jpg", now.Add(-2*time.Hour), now.Add(-2*time.Hour))
+		os.Chtimes("root/b.jpg", now.Add(-1*time.Hour), now.Add(-1*time.Hour))
+
+		report := &renfls.Report{}
+		opts := renfls.Options{Order: test.order, Report: report}
+		if err := opts.WalkRename("root", ".", "trip", renfls.Condition{}); err != nil {
+			t.Errorf("WalkRename(%v) error: %s\n", test.order, err)
+		}
+		for _, entry := range report.Entries {
+			if entry.New != test.want[entry.Old] {
+				t.Errorf("WalkRename(%v) renamed %s to %s, want %s\n",
+					test.order, entry.Old, entry.New, test.want[entry.Old])
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	for _, o := range []renfls.Order{renfls.OrderName, renfls.OrderNatural,
+		renfls.OrderModTime, renfls.OrderSize, renfls.OrderExifDate} {
+		got, err := renfls.ParseOrder(o.String())
+		if err != nil || got != o {
+			t.Errorf("ParseOrder(%q) = %v, %v, want %v\n", o.String(), got, err, o)
+		}
+	}
+	if _, err := renfls.ParseOrder("unknown"); err == nil {
+		t.Errorf("ParseOrder(%q) error = nil\n", "unknown")
+	}
+}
+
+// exifJPEG returns a JPEG header with EXIF DateTimeOriginal.
+func exifJPEG(date string) string {
+	order := binary.LittleEndian
+	var tiff bytes.Buffer
+	tiff.WriteString("II*\x00")
+	binary.Write(&tiff, order, uint32(8))
+	// IFD0 has the offset of the EXIF IFD.
+	binary.Write(&tiff, order, uint16(1))
+	binary.Write(&tiff, order, []uint16{0x8769, 4})
+	binary.Write(&tiff, order, []uint32{1, 26})
+	binary.Write(&tiff, order, uint32(0))
+	// The EXIF IFD has DateTimeOriginal.
+	binary.Write(&tiff, order, uint16(1))
+	binary.Write(&tiff, order, []uint16{0x9003, 2})
+	binary.Write(&tiff, order, []uint32{20, 44})
+	binary.Write(&tiff, order, uint32(0))
+	tiff.WriteString(date + "\x00")
+
+	var jpeg bytes.Buffer
+	jpeg.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&jpeg, binary.BigEndian, uint16(2+6+tiff.Len()))
+	jpeg.WriteString("Exif\x00\x00")
+	jpeg.Write(tiff.Bytes())
+	jpeg.Write([]byte{0xFF, 0xDA})
+	return jpeg.String()
+}
@@ -0,0 +1,63 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const pathNameSeparator = "_"
+
+// PathName is a way to name files by the directories from a root to them.
+// The new file name is used as the name of the root,
+// like "dir3_dir3-1" for "dir3/dir3-1/music.mp3".
+// The zero value names files by the new file name only.
+type PathName struct {
+	// All joins all the directories from the root.
+	All bool
+	// Ancestors joins the nearest directories up to the number
+	// if it is not 0.
+	Ancestors int
+	// Separator is put between the directories. The default is "_".
+	Separator string
+}
+
+// ParsePathName returns the way of a name "all" or a number of ancestors.
+func ParsePathName(name string) (PathName, error) {
+	if name == "all" {
+		return PathName{All: true}, nil
+	}
+	n, e := strconv.Atoi(name)
+	if e != nil || n < 0 {
+		return PathName{}, fmt.Errorf("ParsePathName %q: want all or a number of ancestors", name)
+	}
+	return PathName{Ancestors: n}, nil
+}
+
+// pathName returns the new name of a file in a root
+// joining the directories by the options.
+func (r *renamer) pathName(root, path, newFileName string) string {
+	naming := r.opts.PathName
+	if !naming.All && naming.Ancestors == 0 {
+		return newFileName
+	}
+
+	names := []string{newFileName}
+	rel, e := filepath.Rel(root, filepath.Dir(path))
+	if e == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		names = append(names, strings.Split(filepath.ToSlash(rel), "/")...)
+	}
+	if !naming.All && len(names) > naming.Ancestors {
+		names = names[len(names)-naming.Ancestors:]
+	}
+
+	sep := naming.Separator
+	if sep == "" {
+		sep = pathNameSeparator
+	}
+	return strings.Join(names, sep)
+}
@@ -0,0 +1,42 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestPathName(t *testing.T) {
+	for _, test := range []struct {
+		pathName renfls.PathName
+		want     []string
+	}{
+		{renfls.PathName{}, []string{"root/dir3.txt", "root/dir3-2.mp3", "root/dir3.mp3"}},
+		{renfls.PathName{All: true}, []string{
+			"root/dir3.txt", "root/dir3_dir3-1.mp3", "root/dir3_dir3-1_deep.mp3",
+		}},
+		{renfls.PathName{Ancestors: 1, Separator: "-"}, []string{
+			"root/dir3.txt", "root/dir3-1.mp3", "root/deep.mp3",
+		}},
+		{renfls.PathName{Ancestors: 2}, []string{
+			"root/dir3.txt", "root/dir3_dir3-1.mp3", "root/dir3-1_deep.mp3",
+		}},
+	} {
+		createAlls("root", []string{"dir3/text.txt", "dir3/dir3-1/music.mp3", "dir3/dir3-1/deep/music.mp3"})
+
+		report := &renfls.Report{}
+		opts := renfls.Options{PathName: test.pathName, Report: report}
+		if err := opts.WalkToRootSubDirName("root", "root", renfls.Condition{}); err != nil {
+			t.Errorf("WalkToRootSubDirName(%+v) error: %s\n", test.pathName, err)
+		}
+		for _, path := range test.want {
+			if !isFileExist(path) {
+				t.Errorf("WalkToRootSubDirName(%+v): %s doesn't exist\n", test.pathName, path)
+			}
+		}
+
+		clearTestDir()
+	}
+}
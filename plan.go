@@ -0,0 +1,397 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Kinds of operations.
+const (
+	OpRename  = "rename"
+	OpMkdir   = "mkdir"
+	OpRemove  = "remove"
+	OpLink    = "link"
+	OpCopy    = "copy"
+	OpSymlink = "symlink"
+	OpReflink = "reflink"
+	OpRelink  = "relink"
+)
+
+// Operation is an operation to a file or a directory.
+// New is empty if Op is OpMkdir or OpRemove.
+// OpLink, OpCopy, OpSymlink and OpReflink make New
+// a hard link, a copy, a symbolic link and a clone of Old.
+// OpRelink replaces New with a hard link to Old that has the same content.
+type Operation struct {
+	Op  string `json:"op"`
+	Old string `json:"old"`
+	New string `json:"new,omitempty"`
+}
+
+func (o Operation) String() string {
+	if o.New != "" {
+		return fmt.Sprintf("%s %s -> %s", o.Op, o.Old, o.New)
+	}
+	return fmt.Sprintf("%s %s", o.Op, o.Old)
+}
+
+// Plan returns the operations that WalkRename would perform
+// without touching disk.
+func Plan(root, dest, newFileName string, condition Condition) ([]Operation, error) {
+	return Options{}.Plan(root, dest, newFileName, condition)
+}
+
+// PlanToRootSubDirName returns the operations that WalkToRootSubDirName
+// would perform without touching disk.
+func PlanToRootSubDirName(root, dest string, condition Condition) ([]Operation, error) {
+	return Options{}.PlanToRootSubDirName(root, dest, condition)
+}
+
+// PlanToSubDirsName returns the operations that ToSubDirsName
+// would perform without touching disk.
+func PlanToSubDirsName(root string) ([]Operation, error) {
+	return Options{}.PlanToSubDirsName(root)
+}
+
+// Plan returns the operations that WalkRename with the options
+// would perform without touching disk.
+func (opts Options) Plan(root, dest, newFileName string, condition Condition) ([]Operation, error) {
+	return opts.plan(func(r *renamer) error {
+		return r.walkRenameCondition(root, dest, newFileName, condition)
+	})
+}
+
+// PlanToRootSubDirName returns the operations that WalkToRootSubDirName
+// with the options would perform without touching disk.
+func (opts Options) PlanToRootSubDirName(root, dest string, condition Condition) ([]Operation, error) {
+	return opts.plan(func(r *renamer) error {
+		return r.walkToRootSubDirName(root, dest, condition)
+	})
+}
+
+// PlanToSubDirsName returns the operations that ToSubDirsName
+// with the options would perform without touching disk.
+func (opts Options) PlanToSubDirsName(root string) ([]Operation, error) {
+	return opts.plan(func(r *renamer) error {
+		return r.toSubDirsName(root)
+	})
+}
+
+// plan runs a batch in memory and returns the operations of it.
+func (opts Options) plan(batch func(r *renamer) error) ([]Operation, error) {
+	fs := &planFS{
+		backing:  map[string]string{},
+		created:  map[string]bool{},
+		removed:  map[string]bool{},
+		children: map[string]map[string]bool{},
+	}
+	r, e := opts.newRenamer(fs)
+	if e != nil {
+		return nil, e
+	}
+	e = r.result(batch(r))
+	return fs.ops, e
+}
+
+// planFS is a file system that records operations in memory
+// over the file system of the operating system.
+type planFS struct {
+	ops []Operation
+	// backing maps renamed paths to the real paths on disk.
+	backing map[string]string
+	// created has directories made in memory.
+	created map[string]bool
+	// removed has paths renamed or removed in memory.
+	removed map[string]bool
+	// children has the names of the recorded paths and their ancestors
+	// by their parent directories, so that a directory
+	// and the paths under it are found without scanning all the records.
+	children map[string]map[string]bool
+}
+
+// index adds a recorded path and its ancestors to the children.
+func (fs *planFS) index(path string) {
+	for {
+		dir := filepath.Dir(path)
+		if dir == path {
+			return
+		}
+		names := fs.children[dir]
+		if names == nil {
+			names = map[string]bool{}
+			fs.children[dir] = names
+		}
+		// The ancestors were added with the name.
+		if names[filepath.Base(path)] {
+			return
+		}
+		names[filepath.Base(path)] = true
+		path = dir
+	}
+}
+
+// subtree returns a path and the indexed paths under it.
+func (fs *planFS) subtree(path string) []string {
+	paths := []string{path}
+	for i := 0; i < len(paths); i++ {
+		for name := range fs.children[paths[i]] {
+			paths = append(paths, filepath.Join(paths[i], name))
+		}
+	}
+	return paths
+}
+
+// resolve returns the real path of a path in memory.
+// created is true if the path is a directory made in memory.
+func (fs *planFS) resolve(name string) (real string, created, ok bool) {
+	name = filepath.Clean(name)
+	for q := name; ; {
+		if fs.removed[q] {
+			return "", false, false
+		}
+		if path, ok := fs.backing[q]; ok {
+			rel, _ := filepath.Rel(q, name)
+			return filepath.Join(path, rel), false, true
+		}
+		if fs.created[q] {
+			return "", q == name, q == name
+		}
+		dir := filepath.Dir(q)
+		if dir == q || dir == "." {
+			return name, false, true
+		}
+		q = dir
+	}
+}
+
+func (fs *planFS) Stat(name string) (os.FileInfo, error) {
+	return fs.stat("stat", name, os.Stat)
+}
+
+func (fs *planFS) Lstat(name string) (os.FileInfo, error) {
+	return fs.stat("lstat", name, os.Lstat)
+}
+
+func (fs *planFS) stat(op, name string, stat func(string) (os.FileInfo, error)) (os.FileInfo, error) {
+	real, created, ok := fs.resolve(name)
+	if !ok {
+		return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	if created {
+		return dirInfo(filepath.Base(name)), nil
+	}
+	info, e := stat(real)
+	if e != nil {
+		return nil, e
+	}
+	return namedInfo{info, filepath.Base(name)}, nil
+}
+
+func (fs *planFS) Open(name string) (*os.File, error) {
+	real, created, ok := fs.resolve(name)
+	if !ok || created {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return os.Open(real)
+}
+
+func (fs *planFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	real, created, ok := fs.resolve(dirname)
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: dirname, Err: os.ErrNotExist}
+	}
+
+	names := map[string]bool{}
+	if !created {
+		infos, e := ioutil.ReadDir(real)
+		if e != nil {
+			return nil, e
+		}
+		for _, info := range infos {
+			names[info.Name()] = true
+		}
+	}
+	for name := range fs.children[filepath.Clean(dirname)] {
+		names[name] = true
+	}
+
+	var infos []os.FileInfo
+	for name := range names {
+		if info, e := fs.Lstat(filepath.Join(dirname, name)); e == nil {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+func (fs *planFS) Mkdir(name string, perm os.FileMode) error {
+	if _, e := fs.Lstat(name); e == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	name = filepath.Clean(name)
+	delete(fs.removed, name)
+	fs.created[name] = true
+	fs.index(name)
+	fs.ops = append(fs.ops, Operation{Op: OpMkdir, Old: name})
+	return nil
+}
+
+func (fs *planFS) Rename(oldpath, newpath string) error {
+	real, created, ok := fs.resolve(oldpath)
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	if !created {
+		if _, e := os.Lstat(real); e != nil {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+		}
+	}
+
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	fs.move(oldpath, newpath)
+	if created {
+		fs.created[newpath] = true
+	} else {
+		fs.backing[newpath] = real
+	}
+	delete(fs.removed, newpath)
+	fs.removed[oldpath] = true
+	fs.index(newpath)
+	fs.index(oldpath)
+	fs.ops = append(fs.ops, Operation{Op: OpRename, Old: oldpath, New: newpath})
+	return nil
+}
+
+func (fs *planFS) Copy(oldpath, newpath string) error {
+	return fs.duplicate(OpCopy, oldpath, newpath)
+}
+
+func (fs *planFS) Link(oldname, newname string) error {
+	return fs.duplicate(OpLink, oldname, newname)
+}
+
+func (fs *planFS) Symlink(oldname, newname string) error {
+	return fs.duplicate(OpSymlink, oldname, newname)
+}
+
+func (fs *planFS) Reflink(oldpath, newpath string) error {
+	return fs.duplicate(OpReflink, oldpath, newpath)
+}
+
+// duplicate makes newpath in memory with the content of oldpath.
+func (fs *planFS) duplicate(op, oldpath, newpath string) error {
+	real, created, ok := fs.resolve(oldpath)
+	if !ok || created {
+		return &os.LinkError{Op: op, Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	if _, e := os.Lstat(real); e != nil {
+		return &os.LinkError{Op: op, Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	fs.move(newpath, "")
+	fs.backing[newpath] = real
+	delete(fs.removed, newpath)
+	fs.index(newpath)
+	fs.ops = append(fs.ops, Operation{Op: op, Old: oldpath, New: newpath})
+	return nil
+}
+
+func (fs *planFS) Relink(oldname, newname string) error {
+	fs.ops = append(fs.ops, Operation{Op: OpRelink, Old: filepath.Clean(oldname), New: filepath.Clean(newname)})
+	return nil
+}
+
+func (fs *planFS) Remove(name string) error {
+	infos, e := fs.ReadDir(name)
+	if e == nil && len(infos) > 0 {
+		return &os.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	return fs.RemoveAll(name)
+}
+
+func (fs *planFS) RemoveAll(path string) error {
+	path = filepath.Clean(path)
+	fs.move(path, "")
+	fs.removed[path] = true
+	fs.index(path)
+	fs.ops = append(fs.ops, Operation{Op: OpRemove, Old: path})
+	return nil
+}
+
+// move moves the records of a path and the paths under it to a new path.
+// The records are dropped if newpath is empty.
+// Only the paths indexed under the path are visited.
+func (fs *planFS) move(oldpath, newpath string) {
+	paths := fs.subtree(oldpath)
+	for _, path := range paths {
+		delete(fs.children, path)
+	}
+	if names := fs.children[filepath.Dir(oldpath)]; names != nil {
+		delete(names, filepath.Base(oldpath))
+	}
+
+	for _, path := range paths {
+		rel, _ := under(oldpath, path)
+		moved := false
+		for _, m := range []map[string]bool{fs.created, fs.removed} {
+			if v, ok := m[path]; ok {
+				delete(m, path)
+				if newpath != "" {
+					m[filepath.Join(newpath, rel)] = v
+					moved = true
+				}
+			}
+		}
+		if real, ok := fs.backing[path]; ok {
+			delete(fs.backing, path)
+			if newpath != "" {
+				fs.backing[filepath.Join(newpath, rel)] = real
+				moved = true
+			}
+		}
+		if moved {
+			fs.index(filepath.Join(newpath, rel))
+		}
+	}
+}
+
+// under returns the relative path of path from dir
+// if path is dir or under it.
+func under(dir, path string) (string, bool) {
+	if path == dir {
+		return ".", true
+	}
+	if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return path[len(dir)+1:], true
+	}
+	return "", false
+}
+
+// namedInfo is os.FileInfo with another name.
+type namedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (info namedInfo) Name() string { return info.name }
+
+// dirInfo is os.FileInfo of a directory made in memory.
+type dirInfo string
+
+func (info dirInfo) Name() string       { return string(info) }
+func (info dirInfo) Size() int64        { return 0 }
+func (info dirInfo) Mode() os.FileMode  { return os.ModeDir | os.ModePerm }
+func (info dirInfo) ModTime() time.Time { return time.Time{} }
+func (info dirInfo) IsDir() bool        { return true }
+func (info dirInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,130 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestPlan(t *testing.T) {
+	for _, test := range []struct {
+		mockFiles               []string
+		root, dest, newFileName string
+		condition               renfls.Condition
+		wantOperations          []renfls.Operation
+	}{
+		{
+			[]string{"dir/text.txt", "dir/image.jpg", "dir/text2.txt", "newName.txt"},
+			"root", ".", "newName", renfls.Condition{Exts: []string{"txt"}},
+			[]renfls.Operation{
+				{Op: renfls.OpRename, Old: "root/dir/text.txt", New: "newName.txt"},
+				{Op: renfls.OpRename, Old: "root/dir/text2.txt", New: "newName-2.txt"},
+				{Op: renfls.OpRename, Old: "root/newName.txt", New: "newName-3.txt"},
+			},
+		},
+	} {
+		createAlls(test.root, test.mockFiles)
+
+		operations, err := renfls.Plan(test.root, test.dest, test.newFileName, test.condition)
+		if err != nil {
+			t.Errorf("Plan(%v) error: %s\n", test, err)
+		}
+		if !reflect.DeepEqual(operations, test.wantOperations) {
+			t.Errorf("Plan(%v) = %v, want %v\n", test, operations, test.wantOperations)
+		}
+
+		for _, path := range test.mockFiles {
+			if !isFileExist(filepath.Join(test.root, path)) {
+				t.Errorf("The path %q is changed.\n", path)
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestPlanToRootSubDirName(t *testing.T) {
+	for _, test := range []struct {
+		mockFiles      []string
+		root, dest     string
+		condition      renfls.Condition
+		wantOperations []renfls.Operation
+	}{
+		{
+			[]string{"dir/text.txt", "dir/image.jpg", "dir/sub/text.txt"},
+			"root", "root", renfls.Condition{Exts: []string{"txt"}},
+			[]renfls.Operation{
+				{Op: renfls.OpMkdir, Old: "root/ignore"},
+				{Op: renfls.OpRename, Old: "root/dir", New: "root/ignore/dir"},
+				{Op: renfls.OpRename, Old: "root/ignore/dir/sub/text.txt", New: "root/dir.txt"},
+				{Op: renfls.OpRename, Old: "root/ignore/dir/text.txt", New: "root/dir-2.txt"},
+			},
+		},
+	} {
+		createAlls(test.root, test.mockFiles)
+
+		operations, err := renfls.PlanToRootSubDirName(test.root, test.dest, test.condition)
+		if err != nil {
+			t.Errorf("PlanToRootSubDirName(%v) error: %s\n", test, err)
+		}
+		if !reflect.DeepEqual(operations, test.wantOperations) {
+			t.Errorf("PlanToRootSubDirName(%v) = %v, want %v\n", test, operations, test.wantOperations)
+		}
+
+		for _, path := range test.mockFiles {
+			if !isFileExist(filepath.Join(test.root, path)) {
+				t.Errorf("The path %q is changed.\n", path)
+			}
+		}
+		if isExist(filepath.Join(test.root, "ignore")) {
+			t.Errorf("The ignore directory is created.\n")
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestPlanToSubDirsName(t *testing.T) {
+	createAlls("root", []string{"dir1/text.txt", "dir2/text.txt"})
+
+	operations, err := renfls.PlanToSubDirsName("root")
+	if err != nil {
+		t.Errorf("PlanToSubDirsName() error: %s\n", err)
+	}
+
+	want := []renfls.Operation{
+		{Op: renfls.OpMkdir, Old: "root/fail"},
+		{Op: renfls.OpRename, Old: "root/dir1", New: "root/fail/dir1"},
+		{Op: renfls.OpRename, Old: "root/dir2", New: "root/fail/dir2"},
+		{Op: renfls.OpRename, Old: "root/fail/dir1/text.txt", New: "root/dir1.txt"},
+		{Op: renfls.OpRename, Old: "root/fail/dir2/text.txt", New: "root/dir2.txt"},
+		{Op: renfls.OpRemove, Old: "root/fail"},
+	}
+	if !reflect.DeepEqual(operations, want) {
+		t.Errorf("PlanToSubDirsName() = %v, want %v\n", operations, want)
+	}
+
+	clearTestDir()
+}
+
+func BenchmarkPlanToRootSubDirName(b *testing.B) {
+	// 10k files in 200 directories.
+	for i := 0; i < 200; i++ {
+		for j := 0; j < 50; j++ {
+			createAll(filepath.Join("root", fmt.Sprintf("dir%d/file%d.txt", i, j)))
+		}
+	}
+	defer clearTestDir()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := renfls.PlanToRootSubDirName("root", "root", renfls.Condition{}); err != nil {
+			b.Fatalf("PlanToRootSubDirName() error: %s\n", err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is the ioctl request FICLONE.
+const ficlone = 0x40049409
+
+// reflink clones the content of src to dst sharing the blocks.
+func reflink(dst, src *os.File) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlone, src.Fd())
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
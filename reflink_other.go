@@ -0,0 +1,16 @@
+// Copyright © 2017 shoarai
+
+//go:build !linux
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"errors"
+	"os"
+)
+
+// reflink clones the content of src to dst sharing the blocks.
+func reflink(dst, src *os.File) error {
+	return errors.New("reflink is not supported on this system")
+}
@@ -9,132 +9,479 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
+	"time"
 )
 
 const fileSuffix = "-%d"
 
 // Rename renames a file or a directory and moves it to a directory.
 func Rename(oldPath, dest, newName string) (string, error) {
-	return rename(oldPath, dest, newName)
+	return Options{}.Rename(oldPath, dest, newName)
 }
 
-func rename(oldPath, dest, newName string) (string, error) {
-	if isNotExist(oldPath) {
-		return "", errorNotExist("Rename", oldPath)
+// Options is options to rename files.
+// The zero value renames files in the same way as the package functions.
+type Options struct {
+	// Journal records every operation to files if it is not nil.
+	Journal *Journal
+	// Transactional rolls back every operation of a batch
+	// if the batch fails.
+	Transactional bool
+	// Name is a template of new file names used instead of
+	// the new file name if it is not empty. See Template.
+	Name string
+	// Collision is the policy for new paths that already exist.
+	// If Journal is not nil, the files overwritten by the policy are kept
+	// with the extension ".renfls-bak" to undo the operations.
+	Collision CollisionPolicy
+	// Dedup is the policy for files that have the same content as
+	// the existing files. It takes precedence over Collision.
+	Dedup DedupPolicy
+	// Report is filled with the result of every file if it is not nil.
+	Report *Report
+	// ContinueOnError keeps renaming files after a file fails,
+	// and the failures are returned as *MultiError.
+	ContinueOnError bool
+	// Workers is the number of goroutines that read directories
+	// and stat files concurrently if it is more than 1.
+	// Files are renamed one by one in the same order as
+	// a sequential walk, so that suffixes are deterministic.
+	Workers int
+	// Order is the order in which files in a directory are walked,
+	// so that suffixes are added to files later in the order.
+	Order Order
+	// Mode is the way to put files at new paths.
+	// Directories in roots are not moved unless it is ModeMove.
+	Mode Mode
+	// Verify compares the checksums of files moved across file systems
+	// before removing the originals.
+	Verify bool
+	// CompoundExts are extensions like "tar.gz" kept as a whole
+	// in new names. DefaultCompoundExts is used if it is nil.
+	CompoundExts []string
+	// MinDepth and MaxDepth are the depths of files renamed in walks
+	// if they are not 0. Files directly in a root have depth 1.
+	MinDepth int
+	MaxDepth int
+	// PathName names files by the directories from roots to them
+	// unless Name is not empty.
+	PathName PathName
+	// Symlinks is the way to walk symbolic links.
+	Symlinks SymlinkPolicy
+	// Hidden renames hidden files and walks hidden directories,
+	// whose names begin with a dot, in walks.
+	Hidden bool
+	// Junk is the action for junk files in walks,
+	// which are never renamed.
+	Junk Junk
+	// JunkFiles are the patterns of the names of junk files
+	// matched case-insensitively. DefaultJunkFiles is used if it is nil.
+	JunkFiles []string
+	// Dirs renames the directories under roots in walks instead of files,
+	// deepest first. Directories are renamed in their parent directories
+	// without extensions, so destination directories are not used.
+	// It requires ModeMove.
+	Dirs bool
+}
+
+// Rename renames a file or a directory with the options
+// and moves it to a directory.
+// It returns an empty path if the file is skipped by the collision policy.
+func (opts Options) Rename(oldPath, dest, newName string) (string, error) {
+	var newPath string
+	e := opts.run(func(r *renamer) error {
+		info, e := r.fs.Lstat(oldPath)
+		if e != nil {
+			return errorNotExist("Rename", oldPath, "", ErrSourceNotFound, e)
+		}
+		name := r.newName(filepath.Dir(oldPath), oldPath, info, newName)
+		newPath, e = r.rename(oldPath, dest, name)
+		r.recordRename(oldPath, newPath, "", e)
+		if _, ok := e.(*skipError); ok {
+			return nil
+		}
+		return e
+	})
+	return newPath, e
+}
+
+// WalkRename renames files that match a condition in a root directory
+// with the options and moves them to a destination directory.
+func (opts Options) WalkRename(root, dest, newFileName string, condition Condition) error {
+	return opts.run(func(r *renamer) error {
+		return r.walkRenameCondition(root, dest, newFileName, condition)
+	})
+}
+
+// renamer renames files on a file system.
+type renamer struct {
+	opts Options
+	fs   fileSystem
+	// name is the template of new file names if it is not nil.
+	name *Template
+	// index is the number of files renamed in the root directory.
+	index int
+	// hashes has the hashes of file contents by paths.
+	hashes map[string][]byte
+	// errs has the failures of files if the options continue on error.
+	errs []error
+	// excludeDirs has the patterns of directories not walked.
+	excludeDirs []string
+	// files has the files walked if the options follow symbolic links.
+	files fileSet
+	// backups has the backups of the files overwritten.
+	backups []string
+}
+
+func newRenamer() *renamer {
+	return &renamer{fs: osFS{}, excludeDirs: DefaultExcludeDirs}
+}
+
+func (opts Options) renamer() (*renamer, error) {
+	var fs fileSystem = osFS{verify: opts.Verify}
+	if opts.Journal != nil {
+		fs = &journalFS{fs, opts.Journal.record}
+	}
+	return opts.newRenamer(fs)
+}
+
+func (opts Options) newRenamer(fs fileSystem) (*renamer, error) {
+	if e := opts.Collision.Validate(); e != nil {
+		return nil, e
 	}
-	if isNotExist(dest) {
-		return "", errorNotExist("Rename", dest)
+	r := &renamer{opts: opts, fs: fs}
+	if opts.Name != "" {
+		t, e := ParseTemplate(opts.Name)
+		if e != nil {
+			return nil, e
+		}
+		r.name = t
 	}
+	return r, nil
+}
 
-	_, oldFile := filepath.Split(oldPath)
-	ext := filepath.Ext(oldFile)
-	newPath, e := addSuffixIfExist(dest, newName, ext)
-	if e != nil {
-		return "", e
+// newName returns the new name of a file in a root directory.
+func (r *renamer) newName(root, path string, info os.FileInfo, newFileName string) string {
+	return r.newNameExt(root, path, info, newFileName, r.ext(info.Name()))
+}
+
+// newNameExt returns the new name of a file in a root directory
+// that has an extension.
+func (r *renamer) newNameExt(root, path string, info os.FileInfo, newFileName, ext string) string {
+	r.index++
+	if r.name == nil {
+		return r.pathName(root, path, newFileName)
 	}
+	return r.name.execute(templateData{root, path, info, r.index, ext})
+}
 
-	if e := os.Rename(oldPath, newPath); e != nil {
+func (r *renamer) rename(oldPath, dest, newName string) (string, error) {
+	if e := r.checkExist("Rename", oldPath, dest); e != nil {
 		return "", e
 	}
+
+	_, oldFile := filepath.Split(oldPath)
+	return r.renameExt(oldPath, dest, newName, r.ext(oldFile))
+}
+
+// renameExt renames a file to a new name with an extension
+// and moves it to a directory.
+func (r *renamer) renameExt(oldPath, dest, newName, ext string) (string, error) {
+	newPath := filepath.Join(dest, newName) + ext
+	if !r.isNotExist(newPath) {
+		if p, ok, e := r.dedup(oldPath, dest, newName, ext); ok || e != nil {
+			return p, e
+		}
+		return r.collide(oldPath, dest, newName, ext)
+	}
+	return r.place(oldPath, newPath)
+}
+
+func (r *renamer) move(oldPath, newPath string) (string, error) {
+	if e := r.fs.Rename(oldPath, newPath); e != nil {
+		return "", &RenameError{"Rename", oldPath, newPath, underlying(e)}
+	}
+	if sum, ok := r.hashes[oldPath]; ok {
+		r.hashes[newPath] = sum
+		delete(r.hashes, oldPath)
+	} else {
+		delete(r.hashes, newPath)
+	}
 	return newPath, nil
 }
 
-func addSuffixIfExist(dir, file, ext string) (string, error) {
+func (r *renamer) addSuffixIfExist(dir, file, ext string) (string, error) {
 	path := filepath.Join(dir, file)
-	if p := path + ext; isNotExist(p) {
+	if p := path + ext; r.isNotExist(p) {
 		return p, nil
 	}
 
-	for i := 2; i < math.MaxInt16; i++ {
-		suff := fmt.Sprintf(fileSuffix, i)
-		if p := path + suff + ext; isNotExist(p) {
+	format, start := r.opts.Collision.suffix()
+	for i := start; i < math.MaxInt16; i++ {
+		suff := fmt.Sprintf(format, i)
+		if p := path + suff + ext; r.isNotExist(p) {
 			return p, nil
 		}
 	}
-	return "", fmt.Errorf("Add file suffix failed")
+	return "", ErrSuffixExhausted
 }
 
 // WalkRenameAll renames all files in a root directory
 // and moves them to a destination directory.
 func WalkRenameAll(root, dest, newFileName string) error {
-	return walkRename(root, dest, newFileName, nil)
+	return newRenamer().walkRename(root, dest, newFileName, nil)
 }
 
 // Condition is condition to rename files.
 type Condition struct {
-	Exts   []string
-	Reg    string
+	// Exts are extensions like "jpg" or "tar.gz" matched
+	// case-insensitively unless CaseSensitive is true.
+	Exts          []string
+	CaseSensitive bool
+	Reg           string
+	// Globs are shell patterns matched against the slash-separated path
+	// relative to the root, like "**/raw/*.CR2".
+	// A pattern without slashes matches the file name.
+	Globs  []string
 	Ignore bool
+	// Include selects files matching any of the rules if it is not empty,
+	// and then Exclude leaves files matching any of the rules.
+	Include []Rule
+	Exclude []Rule
+	// MinSize and MaxSize select files by size in bytes if they are not 0.
+	MinSize int64
+	MaxSize int64
+	// ModifiedAfter and ModifiedBefore select files modified
+	// after and before the times if they are not zero.
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// Types selects files of any of the types if it is not empty.
+	Types []FileType
+	// ExcludeDirs are glob patterns of directories not walked like Globs.
+	// DefaultExcludeDirs is used if it is nil.
+	ExcludeDirs []string
+	// Filter selects files in addition to the other fields
+	// if it is not nil. See ParseFilter.
+	Filter Filter
 }
 
 // WalkRename renames files that match a condition in a root directory
 // and moves them to a destination directory.
 func WalkRename(root, dest, newFileName string, condition Condition) error {
-	var reg *regexp.Regexp
+	return Options{}.WalkRename(root, dest, newFileName, condition)
+}
 
-	if condition.Reg != "" {
-		var e error
-		reg, e = regexp.Compile(condition.Reg)
-		if e != nil {
-			return e
+func (r *renamer) walkRenameCondition(root, dest, newFileName string, condition Condition) error {
+	match, e := condition.matcher()
+	if e != nil {
+		return e
+	}
+	return r.walkMatch(root, dest, newFileName, match, condition.excludeDirs())
+}
+
+func (condition Condition) excludeDirs() []string {
+	if condition.ExcludeDirs == nil {
+		return DefaultExcludeDirs
+	}
+	return condition.ExcludeDirs
+}
+
+// matcher returns whether a file needs to be renamed
+// and the rule that decided it.
+// rel is the slash-separated path of the file relative to the root.
+type matcher func(rel string, info os.FileInfo) (bool, string)
+
+// matcher returns a matcher of the condition.
+func (condition Condition) matcher() (matcher, error) {
+	base := Rule{condition.Exts, condition.Reg, condition.Globs}
+	baseMatch, e := base.matcher(condition.CaseSensitive)
+	if e != nil {
+		return nil, e
+	}
+	includes, e := matchers(condition.Include, condition.CaseSensitive)
+	if e != nil {
+		return nil, e
+	}
+	excludes, e := matchers(condition.Exclude, condition.CaseSensitive)
+	if e != nil {
+		return nil, e
+	}
+
+	isMatch := func(rel string, info os.FileInfo) (bool, string) {
+		if base.isEmpty() {
+			return true, "all"
 		}
+		return baseMatch(rel, info)
 	}
 
-	isMatch := func(info os.FileInfo) bool {
-		if reg == nil && (condition.Exts == nil || len(condition.Exts) == 0) {
-			return true
+	match := func(rel string, info os.FileInfo) (bool, string) {
+		ok, rule := isMatch(rel, info)
+		if condition.Ignore {
+			if ok {
+				return false, "ignore " + rule
+			}
+			ok, rule = true, "not ignored"
 		}
-		if reg != nil && reg.MatchString(info.Name()) {
-			return true
+		if !ok {
+			return ok, rule
 		}
-		if condition.Exts != nil && len(condition.Exts) > 0 && hasExt(info.Name(), condition.Exts) {
-			return true
+
+		if len(includes) > 0 {
+			included, pattern := matchAny(includes, rel, info)
+			if !included {
+				return false, "not included"
+			}
+			if rule == "all" {
+				rule = "include " + pattern
+			}
+		}
+		if excluded, pattern := matchAny(excludes, rel, info); excluded {
+			return false, "exclude " + pattern
 		}
-		return false
+		if matched, excluded := condition.matchMetadata(info); !matched {
+			return false, excluded
+		}
+		return ok, rule
+	}
+	if condition.Filter == nil {
+		return match, nil
 	}
 
-	return walkRename(root, dest, newFileName, func(info os.FileInfo) bool {
-		if condition.Ignore {
-			return !isMatch(info)
-		} else {
-			return isMatch(info)
+	return func(rel string, info os.FileInfo) (bool, string) {
+		ok, rule := match(rel, info)
+		if !ok {
+			return ok, rule
 		}
-	})
+		if !condition.Filter.Match(File{rel, info}) {
+			return false, "not where " + filterString(condition.Filter)
+		}
+		if rule == "all" {
+			return true, "where " + filterString(condition.Filter)
+		}
+		return true, rule + " where " + filterString(condition.Filter)
+	}, nil
 }
 
 // NeedRename returns whether the file needs to be rename.
+// It is a Filter of file infos.
 type NeedRename func(info os.FileInfo) bool
 
-func walkRename(root, dest, newFileName string, needRename NeedRename) error {
-	if isNotExist(root) {
-		return errorNotExist("RenameAll", root)
-	}
-	if isNotExist(dest) {
-		return errorNotExist("RenameAll", dest)
+// Match returns f(file.Info).
+func (f NeedRename) Match(file File) bool { return f(file.Info) }
+
+func walkRename(root, dest, newFileName string, filter Filter) error {
+	return newRenamer().walkRename(root, dest, newFileName, filter)
+}
+
+func (r *renamer) walkRename(root, dest, newFileName string, filter Filter) error {
+	match := func(rel string, info os.FileInfo) (bool, string) { return true, "all" }
+	if filter != nil {
+		match = func(rel string, info os.FileInfo) (bool, string) {
+			if filter.Match(File{rel, info}) {
+				return true, filterString(filter)
+			}
+			return false, "no match"
+		}
 	}
-	return filepath.Walk(root, walkRenameFunc(dest, newFileName, needRename))
+	return r.walkMatch(root, dest, newFileName, match, DefaultExcludeDirs)
 }
 
-func walkRenameFunc(dest, newFileName string, needRename NeedRename) filepath.WalkFunc {
-	if needRename == nil {
-		needRename = func(nfo os.FileInfo) bool { return true }
+func (r *renamer) walkMatch(root, dest, newFileName string, match matcher, excludeDirs []string) error {
+	if e := r.checkExist("RenameAll", root, dest); e != nil {
+		return e
 	}
+	r.index = 0
+	r.excludeDirs = excludeDirs
+	if r.opts.Dirs {
+		return r.walkRenameDirs(root, newFileName, match)
+	}
+	return r.walk(root, r.walkRenameFunc(root, dest, newFileName, match))
+}
+
+func (r *renamer) walkRenameFunc(root, dest, newFileName string, match matcher) filepath.WalkFunc {
+	return r.walkFilesFunc(root, match, func(path string, info os.FileInfo, rule string) error {
+		name := r.newName(root, path, info, newFileName)
+		newPath, err := r.rename(path, dest, name)
+		return r.renamed(path, newPath, rule, err)
+	})
+}
 
+// walkFilesFunc returns a function to walk a root that calls fn
+// for files that match and records the other files.
+func (r *renamer) walkFilesFunc(root string, match matcher,
+	fn func(path string, info os.FileInfo, rule string) error) filepath.WalkFunc {
 	return func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			r.record(ReportEntry{Old: path, Status: StatusFailed, Err: err})
+			return r.fail(err)
+		}
+		if info.IsDir() {
+			if skip, rule := r.skipDir(root, path); skip {
+				r.record(ReportEntry{Old: path, Rule: rule, Status: StatusIgnored})
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		rel = filepath.ToSlash(rel)
+		// Junk files are handled after they are selected like the other files.
+		pattern := r.junkPattern(info.Name())
+		// The root itself is renamed even if it is hidden.
+		if pattern == "" && !r.opts.Hidden && rel != "." && isHidden(info.Name()) {
+			r.record(ReportEntry{Old: path, Rule: "hidden", Status: StatusIgnored})
+			return nil
+		}
+		if depth(rel) < r.opts.MinDepth {
+			rule := fmt.Sprintf("shallower than min depth %d", r.opts.MinDepth)
+			r.record(ReportEntry{Old: path, Rule: rule, Status: StatusIgnored})
+			return nil
 		}
-		if info.IsDir() || !needRename(info) {
+		// The root itself is matched by its name.
+		if rel == "." {
+			rel = info.Name()
+		}
+		old := path
+		if isSymlink(info) {
+			var rule string
+			if path, info, rule = r.resolve(path, info); rule != "" {
+				r.record(ReportEntry{Old: old, Rule: rule, Status: StatusIgnored})
+				return nil
+			}
+		}
+		ok, rule := match(rel, info)
+		if !ok {
+			r.record(ReportEntry{Old: old, Rule: rule, Status: StatusIgnored})
 			return nil
 		}
-		if _, err := Rename(path, dest, newFileName); err != nil {
-			return err
+		if pattern != "" {
+			return r.junk(old, pattern)
+		}
+		// Files can be walked more than once through symbolic links.
+		followed := r.opts.Symlinks == SymlinkTarget || r.opts.Symlinks == SymlinkFollow
+		if followed && !r.files.add(info) {
+			r.record(ReportEntry{Old: old, Rule: "walked through another path", Status: StatusIgnored})
+			return nil
 		}
-		return nil
+		return fn(path, info, rule)
 	}
 }
 
+// renamed records the result of a file
+// and returns the error to stop renaming files.
+func (r *renamer) renamed(path, newPath, rule string, err error) error {
+	r.recordRename(path, newPath, rule, err)
+	if err != nil {
+		if _, ok := err.(*skipError); ok {
+			return nil
+		}
+		return r.fail(err)
+	}
+	return nil
+}
+
 // RenamePattern renames all files matching pattern in root
 // and moves these to a directory.
 func RenamePattern(root, dest, newFileName, pattern string) error {
@@ -142,33 +489,25 @@ func RenamePattern(root, dest, newFileName, pattern string) error {
 	if e != nil {
 		return e
 	}
-	return walkRename(root, dest, newFileName, func(info os.FileInfo) bool {
+	return walkRename(root, dest, newFileName, NeedRename(func(info os.FileInfo) bool {
 		return reg.MatchString(info.Name())
-	})
+	}))
 }
 
 // RenameExt renames all files matching extensions in root
 // and moves these to a directory.
 func RenameExt(root, dest, newFileName string, exts []string) error {
-	return walkRename(root, dest, newFileName, func(info os.FileInfo) bool {
-		return hasExt(info.Name(), exts)
-	})
+	return walkRename(root, dest, newFileName, NeedRename(func(info os.FileInfo) bool {
+		return hasExt(info.Name(), exts, false)
+	}))
 }
 
 // RenameIgnoreExt renames all files not matching extension ins root
 // and moves these to a directory.
 func RenameIgnoreExt(root, dest, newFileName string, exts []string) error {
-	return walkRename(root, dest, newFileName, func(info os.FileInfo) bool {
-		return !hasExt(info.Name(), exts)
-	})
-}
-
-func hasExt(file string, exts []string) bool {
-	ext := filepath.Ext(file)
-	if strings.HasPrefix(ext, ".") {
-		ext = ext[1:]
-	}
-	return contains(exts, ext)
+	return walkRename(root, dest, newFileName, NeedRename(func(info os.FileInfo) bool {
+		return !hasExt(info.Name(), exts, false)
+	}))
 }
 
 func contains(strs []string, s string) bool {
@@ -186,11 +525,24 @@ func RenameCondition(root, dest, newFileName string, needRename NeedRename) erro
 	return walkRename(root, dest, newFileName, needRename)
 }
 
-func errorNotExist(funcName, path string) error {
-	return fmt.Errorf("%s %s: no such file or directory", funcName, path)
+// RenameFilter renames all files matching a filter in root
+// and moves these to a directory.
+func RenameFilter(root, dest, newFileName string, filter Filter) error {
+	return walkRename(root, dest, newFileName, filter)
+}
+
+// checkExist returns an error if a source or a destination doesn't exist.
+func (r *renamer) checkExist(op, source, dest string) error {
+	if _, e := r.fs.Stat(source); e != nil {
+		return errorNotExist(op, source, dest, ErrSourceNotFound, e)
+	}
+	if _, e := r.fs.Stat(dest); e != nil {
+		return errorNotExist(op, source, dest, ErrDestNotFound, e)
+	}
+	return nil
 }
 
-func isNotExist(path string) bool {
-	_, e := os.Stat(path)
+func (r *renamer) isNotExist(path string) bool {
+	_, e := r.fs.Stat(path)
 	return e != nil
 }
@@ -31,8 +31,8 @@ func TestRename(t *testing.T) {
 		{"dir1/text.txt", ".", "new1", "new1.txt"},
 		{"dir1/image.jpg", ".", "new1", "new1.jpg"},
 		{"dir1/ミュージック　.mp3", ".", "　新　", "　新　.mp3"},
-		{"dir1/.no", ".", "new1", "new1.no"},
-		{"dir1/file", ".", "new1", "new1"},
+		{"dir1/.no", ".", "new1", "new1"},
+		{"dir1/file", ".", "new1", "new1-2"},
 		{"dir2/a.txt", ".", "new text2", "new text2.txt"},
 		{"dir2/b.txt", ".", "new text2", "new text2-2.txt"},
 		{"dir2/c.txt", ".", "new text2", "new text2-3.txt"},
@@ -0,0 +1,109 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+// Status is the result of a file.
+type Status string
+
+// Results of files.
+const (
+	StatusRenamed Status = "renamed"
+	StatusSkipped Status = "skipped"
+	StatusIgnored Status = "ignored"
+	StatusFailed  Status = "failed"
+	StatusDeleted Status = "deleted"
+)
+
+// Report is the result of renaming files.
+type Report struct {
+	Entries []ReportEntry
+
+	Renamed int
+	Skipped int
+	Ignored int
+	Failed  int
+	Deleted int
+}
+
+// ReportEntry is the result of a file.
+type ReportEntry struct {
+	Old string
+	// New is the new path if the file is renamed.
+	New string
+	// Rule is the rule of the condition that matched the file.
+	Rule   string
+	Status Status
+	// Reason is the reason why the file is skipped.
+	Reason string
+	Err    error
+}
+
+func (report *Report) add(entry ReportEntry) {
+	report.Entries = append(report.Entries, entry)
+	switch entry.Status {
+	case StatusRenamed:
+		report.Renamed++
+	case StatusSkipped:
+		report.Skipped++
+	case StatusIgnored:
+		report.Ignored++
+	case StatusFailed:
+		report.Failed++
+	case StatusDeleted:
+		report.Deleted++
+	}
+}
+
+func (r *renamer) record(entry ReportEntry) {
+	if r.opts.Report != nil {
+		r.opts.Report.add(entry)
+	}
+}
+
+// recordRename records the result of renaming a file.
+func (r *renamer) recordRename(oldPath, newPath, rule string, e error) {
+	entry := ReportEntry{Old: oldPath, New: newPath, Rule: rule, Status: StatusRenamed}
+	if skip, ok := e.(*skipError); ok {
+		entry.Status = StatusSkipped
+		entry.Reason = skip.reason
+	} else if e != nil {
+		entry.Status = StatusFailed
+		entry.Err = e
+	}
+	r.record(entry)
+}
+
+// WalkRenameReport renames files in the same way as WalkRename
+// and returns the result of every file.
+// Use Condition for the result of RenamePattern, RenameExt and so on.
+func WalkRenameReport(root, dest, newFileName string, condition Condition) (*Report, error) {
+	report := &Report{}
+	e := Options{Report: report}.WalkRename(root, dest, newFileName, condition)
+	return report, e
+}
+
+// WalkToRootDirNameReport renames files in the same way as WalkToRootDirName
+// and returns the result of every file.
+// Use Condition{} for the result of ToDirName.
+func WalkToRootDirNameReport(root, dest string, condition Condition) (*Report, error) {
+	report := &Report{}
+	e := Options{Report: report}.WalkToRootDirName(root, dest, condition)
+	return report, e
+}
+
+// WalkToRootSubDirNameReport renames files in the same way as
+// WalkToRootSubDirName and returns the result of every file.
+func WalkToRootSubDirNameReport(root, dest string, condition Condition) (*Report, error) {
+	report := &Report{}
+	e := Options{Report: report}.WalkToRootSubDirName(root, dest, condition)
+	return report, e
+}
+
+// ToSubDirsNameReport renames files in the same way as ToSubDirsName
+// and returns the result of every file.
+func ToSubDirsNameReport(root string) (*Report, error) {
+	report := &Report{}
+	e := Options{Report: report}.ToSubDirsName(root)
+	return report, e
+}
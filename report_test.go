@@ -0,0 +1,58 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestWalkRenameReport(t *testing.T) {
+	createAlls("root", []string{"dir/text.txt", "dir/image.jpg", "dir/text2.txt"})
+	createAll("new.txt")
+
+	report, err := renfls.WalkRenameReport("root", ".", "new", renfls.Condition{Exts: []string{"txt"}})
+	if err != nil {
+		t.Errorf("WalkRenameReport() error: %s\n", err)
+	}
+
+	want := []renfls.ReportEntry{
+		{Old: "root/dir/image.jpg", Rule: "no match", Status: renfls.StatusIgnored},
+		{Old: "root/dir/text.txt", New: "new-2.txt", Rule: "ext txt", Status: renfls.StatusRenamed},
+		{Old: "root/dir/text2.txt", New: "new-3.txt", Rule: "ext txt", Status: renfls.StatusRenamed},
+	}
+	if len(report.Entries) != len(want) {
+		t.Fatalf("WalkRenameReport() entries = %v, want %v\n", report.Entries, want)
+	}
+	for i, entry := range report.Entries {
+		if entry != want[i] {
+			t.Errorf("WalkRenameReport() entry = %+v, want %+v\n", entry, want[i])
+		}
+	}
+	if report.Renamed != 2 || report.Ignored != 1 || report.Skipped != 0 || report.Failed != 0 {
+		t.Errorf("WalkRenameReport() counts = %+v\n", report)
+	}
+
+	clearTestDir()
+}
+
+func TestReportSkipped(t *testing.T) {
+	createAlls("root", []string{"dir/text.txt"})
+	createAll("new.txt")
+
+	report := &renfls.Report{}
+	opts := renfls.Options{
+		Collision: renfls.CollisionPolicy{Strategy: renfls.CollisionSkip},
+		Report:    report,
+	}
+	if err := opts.WalkRename("root", ".", "new", renfls.Condition{}); err != nil {
+		t.Errorf("WalkRename() error: %s\n", err)
+	}
+
+	if report.Skipped != 1 || report.Entries[0].Reason != "new.txt already exists" {
+		t.Errorf("WalkRename() report = %+v, want a skipped file\n", report)
+	}
+
+	clearTestDir()
+}
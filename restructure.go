@@ -0,0 +1,218 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Nest is a way to move flat files into directories made from their names,
+// like "2017-05-01_trip.jpg" into "2017/05/trip/".
+type Nest struct {
+	// Reg is a regular expression with named groups like `(?P<year>\d{4})`
+	// matched against file names without extensions.
+	Reg string
+	// Pattern is a pattern like "{year}-{month}-{day}_{title}"
+	// used instead of Reg if Reg is empty.
+	// Each variable matches one or more characters,
+	// and "{{" and "}}" are a literal brace.
+	Pattern string
+	// Dir is a path of directories with the groups like "{year}/{month}/{title}".
+	Dir string
+	// Name is a new file name with the groups.
+	// The file name is kept if it is empty.
+	// {name} is the file name without the extension in Dir and Name.
+	Name string
+}
+
+// nester is a parsed Nest.
+type nester struct {
+	reg  *regexp.Regexp
+	dir  []templatePart
+	name []templatePart
+}
+
+func (nest Nest) compile() (*nester, error) {
+	expr := nest.Reg
+	if expr == "" {
+		if nest.Pattern == "" {
+			return nil, errors.New("Nest: Reg or Pattern is required")
+		}
+		var e error
+		if expr, e = patternRegexp(nest.Pattern); e != nil {
+			return nil, fmt.Errorf("Nest pattern %q: %s", nest.Pattern, e)
+		}
+	}
+	reg, e := regexp.Compile(expr)
+	if e != nil {
+		return nil, fmt.Errorf("Nest: %s", e)
+	}
+
+	groups := map[string]bool{"name": true}
+	for _, group := range reg.SubexpNames() {
+		groups[group] = group != ""
+	}
+	variable := func(s string) (templatePart, error) {
+		if !groups[s] {
+			return templatePart{}, fmt.Errorf("unknown group %q", s)
+		}
+		return templatePart{variable: s}, nil
+	}
+	dir, e := scanTemplate(nest.Dir, variable)
+	if e != nil {
+		return nil, fmt.Errorf("Nest dir %q: %s", nest.Dir, e)
+	}
+	name, e := scanTemplate(nest.Name, variable)
+	if e != nil {
+		return nil, fmt.Errorf("Nest name %q: %s", nest.Name, e)
+	}
+	return &nester{reg, dir, name}, nil
+}
+
+var groupName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// patternRegexp returns a regular expression of a pattern
+// with a named group for each variable.
+func patternRegexp(pattern string) (string, error) {
+	parts, e := scanTemplate(pattern, func(s string) (templatePart, error) {
+		if !groupName.MatchString(s) {
+			return templatePart{}, fmt.Errorf("invalid variable %q", s)
+		}
+		return templatePart{variable: s}, nil
+	})
+	if e != nil {
+		return "", e
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, part := range parts {
+		if part.variable == "" {
+			b.WriteString(regexp.QuoteMeta(part.literal))
+		} else {
+			b.WriteString("(?P<" + part.variable + ">.+?)")
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// expand returns the directory and the new name of a file name
+// without the extension. ok is false if the file doesn't match.
+func (n *nester) expand(name string) (dir, newName string, ok bool) {
+	match := n.reg.FindStringSubmatch(name)
+	if match == nil {
+		return "", "", false
+	}
+	groups := map[string]string{"name": name}
+	for i, group := range n.reg.SubexpNames() {
+		if group != "" {
+			// Groups can't climb up directories.
+			if match[i] == "." || match[i] == ".." {
+				return "", "", false
+			}
+			groups[group] = match[i]
+		}
+	}
+
+	execute := func(parts []templatePart) string {
+		var b strings.Builder
+		for _, part := range parts {
+			if part.variable == "" {
+				b.WriteString(part.literal)
+			} else {
+				b.WriteString(groups[part.variable])
+			}
+		}
+		return b.String()
+	}
+	newName = name
+	if len(n.name) > 0 {
+		newName = execute(n.name)
+	}
+	return execute(n.dir), newName, true
+}
+
+// Restructure moves files that match a condition in a root directory
+// into directories made from their names in a destination directory.
+func Restructure(root, dest string, nest Nest, condition Condition) error {
+	return Options{}.Restructure(root, dest, nest, condition)
+}
+
+// Restructure moves files that match a condition in a root directory
+// into directories made from their names with the options
+// in a destination directory.
+func (opts Options) Restructure(root, dest string, nest Nest, condition Condition) error {
+	return opts.run(func(r *renamer) error {
+		return r.restructure(root, dest, nest, condition)
+	})
+}
+
+// PlanRestructure returns the operations that Restructure with the options
+// would perform without touching disk.
+func (opts Options) PlanRestructure(root, dest string, nest Nest, condition Condition) ([]Operation, error) {
+	return opts.plan(func(r *renamer) error {
+		return r.restructure(root, dest, nest, condition)
+	})
+}
+
+// walkedFile is a file collected in a walk.
+type walkedFile struct {
+	path string
+	info os.FileInfo
+	rule string
+}
+
+func (r *renamer) restructure(root, dest string, nest Nest, condition Condition) error {
+	n, e := nest.compile()
+	if e != nil {
+		return e
+	}
+	match, e := condition.matcher()
+	if e != nil {
+		return e
+	}
+	if e := r.checkExist("Restructure", root, dest); e != nil {
+		return e
+	}
+
+	// Files are collected before moving them,
+	// so that files moved into directories in the root are not walked again.
+	var files []walkedFile
+	r.excludeDirs = condition.excludeDirs()
+	e = r.walk(root, r.walkFilesFunc(root, match, func(path string, info os.FileInfo, rule string) error {
+		files = append(files, walkedFile{path, info, rule})
+		return nil
+	}))
+	if e != nil {
+		return e
+	}
+
+	for _, file := range files {
+		ext := r.ext(file.info.Name())
+		dir, newName, ok := n.expand(strings.TrimSuffix(file.info.Name(), ext))
+		if !ok {
+			r.record(ReportEntry{Old: file.path, Rule: "no match " + n.reg.String(), Status: StatusIgnored})
+			continue
+		}
+
+		newDir := filepath.Join(dest, dir)
+		var newPath string
+		e := mkdirAll(r.fs, newDir)
+		if e != nil {
+			e = &RenameError{"Restructure", file.path, newDir, underlying(e)}
+		} else {
+			newPath, e = r.rename(file.path, newDir, newName)
+		}
+		if e := r.renamed(file.path, newPath, file.rule, e); e != nil {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestRestructure(t *testing.T) {
+	for _, test := range []struct {
+		nest renfls.Nest
+		want []string
+	}{
+		{renfls.Nest{Pattern: "{year}-{month}-{day}_{title}", Dir: "{year}/{month}/{title}"}, []string{
+			"dest/2017/05/trip/2017-05-01_trip.jpg", "dest/2018/01/party/2018-01-02_party.tar.gz", "root/notes.txt",
+		}},
+		{renfls.Nest{Reg: `^(?P<year>\d{4})-\d{2}-(?P<day>\d{2})`, Dir: "{year}", Name: "{day}_{name}"}, []string{
+			"dest/2017/01_2017-05-01_trip.jpg", "dest/2018/02_2018-01-02_party.tar.gz", "root/notes.txt",
+		}},
+	} {
+		createAlls("root", []string{"2017-05-01_trip.jpg", "sub/2018-01-02_party.tar.gz", "notes.txt"})
+		os.Mkdir("dest", os.ModePerm)
+
+		report := &renfls.Report{}
+		opts := renfls.Options{Report: report}
+		if err := opts.Restructure("root", "dest", test.nest, renfls.Condition{}); err != nil {
+			t.Errorf("Restructure(%+v) error: %s\n", test.nest, err)
+		}
+		for _, path := range test.want {
+			if !isFileExist(path) {
+				t.Errorf("Restructure(%+v): %s doesn't exist\n", test.nest, path)
+			}
+		}
+		if report.Ignored != 1 {
+			t.Errorf("Restructure(%+v): %d files ignored, want 1\n", test.nest, report.Ignored)
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestRestructureError(t *testing.T) {
+	createAlls("root", []string{"2017-05-01_trip.jpg"})
+	defer clearTestDir()
+
+	for _, nest := range []renfls.Nest{
+		{Dir: "{year}"},
+		{Pattern: "{year", Dir: "{year}"},
+		{Pattern: "{year}-{month}", Dir: "{day}"},
+		{Reg: `(?P<year>\d{4}`, Dir: "{year}"},
+	} {
+		if err := renfls.Restructure("root", "root", nest, renfls.Condition{}); err == nil {
+			t.Errorf("Restructure(%+v) = nil, want error\n", nest)
+		}
+	}
+	if !isFileExist("root/2017-05-01_trip.jpg") {
+		t.Errorf("Restructure moved a file with an invalid nest\n")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rule is patterns of files in Condition.Include and Condition.Exclude.
+// A file matches a rule if it matches any of the patterns.
+type Rule struct {
+	Exts  []string
+	Reg   string
+	Globs []string
+}
+
+// ParseRule parses a rule like "ext:jpg,png", "reg:^thumb" or "glob:**/*.jpg".
+// A rule without a kind is a glob.
+func ParseRule(s string) (Rule, error) {
+	kind, pattern := "glob", s
+	if i := strings.Index(s, ":"); i >= 0 {
+		kind, pattern = s[:i], s[i+1:]
+	}
+	if pattern == "" {
+		return Rule{}, fmt.Errorf("ParseRule %q: empty pattern", s)
+	}
+
+	switch kind {
+	case "ext":
+		return Rule{Exts: strings.Split(pattern, ",")}, nil
+	case "reg":
+		return Rule{Reg: pattern}, nil
+	case "glob":
+		return Rule{Globs: []string{pattern}}, nil
+	}
+	return Rule{}, fmt.Errorf("ParseRule %q: unknown kind %q, want ext, reg or glob", s, kind)
+}
+
+func (rule Rule) isEmpty() bool {
+	return rule.Reg == "" && len(rule.Exts) == 0 && len(rule.Globs) == 0
+}
+
+// matcher returns a matcher of the rule
+// that returns the pattern matching a file.
+func (rule Rule) matcher(caseSensitive bool) (matcher, error) {
+	var reg *regexp.Regexp
+	if rule.Reg != "" {
+		var e error
+		reg, e = regexp.Compile(rule.Reg)
+		if e != nil {
+			return nil, e
+		}
+	}
+	for _, glob := range rule.Globs {
+		if e := checkGlob(glob); e != nil {
+			return nil, fmt.Errorf("glob %q: %s", glob, e)
+		}
+	}
+
+	return func(rel string, info os.FileInfo) (bool, string) {
+		if reg != nil && reg.MatchString(info.Name()) {
+			return true, "reg " + rule.Reg
+		}
+		for _, glob := range rule.Globs {
+			if matchGlob(glob, rel) {
+				return true, "glob " + glob
+			}
+		}
+		if len(rule.Exts) > 0 && hasExt(info.Name(), rule.Exts, caseSensitive) {
+			return true, "ext " + strings.Join(rule.Exts, ",")
+		}
+		return false, "no match"
+	}, nil
+}
+
+// matchers returns the matchers of rules.
+func matchers(rules []Rule, caseSensitive bool) ([]matcher, error) {
+	matchers := make([]matcher, len(rules))
+	for i, rule := range rules {
+		m, e := rule.matcher(caseSensitive)
+		if e != nil {
+			return nil, e
+		}
+		matchers[i] = m
+	}
+	return matchers, nil
+}
+
+// matchAny returns the pattern of the first matcher matching a file.
+func matchAny(matchers []matcher, rel string, info os.FileInfo) (bool, string) {
+	for _, m := range matchers {
+		if ok, pattern := m(rel, info); ok {
+			return true, pattern
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,122 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestIncludeExclude(t *testing.T) {
+	createAlls("root", []string{
+		"dir/a.jpg", "dir/thumb_a.jpg", "dir/b.PNG", "dir/thumbs/c.jpg", "dir/text.txt",
+	})
+
+	// All images except thumbnails.
+	condition := renfls.Condition{
+		Include: []renfls.Rule{{Exts: []string{"jpg", "png"}}},
+		Exclude: []renfls.Rule{{Reg: "^thumb"}, {Globs: []string{"**/thumbs/*"}}},
+	}
+	report := &renfls.Report{}
+	opts := renfls.Options{Report: report}
+	if _, err := opts.Plan("root", ".", "new", condition); err != nil {
+		t.Errorf("Plan() error: %s\n", err)
+	}
+
+	want := map[string]string{
+		"root/dir/a.jpg":        "include ext jpg,png",
+		"root/dir/b.PNG":        "include ext jpg,png",
+		"root/dir/text.txt":     "not included",
+		"root/dir/thumb_a.jpg":  "exclude reg ^thumb",
+		"root/dir/thumbs/c.jpg": "exclude glob **/thumbs/*",
+	}
+	if len(report.Entries) != len(want) {
+		t.Errorf("Plan() entries = %v, want %v\n", report.Entries, want)
+	}
+	for _, entry := range report.Entries {
+		if entry.Rule != want[entry.Old] {
+			t.Errorf("Plan() rule of %s = %q, want %q\n", entry.Old, entry.Rule, want[entry.Old])
+		}
+	}
+
+	clearTestDir()
+}
+
+func TestParseRule(t *testing.T) {
+	for s, want := range map[string]renfls.Rule{
+		"ext:jpg,png":   {Exts: []string{"jpg", "png"}},
+		"reg:^thumb":    {Reg: "^thumb"},
+		"glob:**/*.jpg": {Globs: []string{"**/*.jpg"}},
+		"*.jpg":         {Globs: []string{"*.jpg"}},
+	} {
+		got, err := renfls.ParseRule(s)
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("ParseRule(%q) = %v, %v, want %v\n", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"ext:", "size:10"} {
+		if _, err := renfls.ParseRule(s); err == nil {
+			t.Errorf("ParseRule(%q) error = nil\n", s)
+		}
+	}
+}
+
+func TestExcludeDirs(t *testing.T) {
+	createAlls("root", []string{
+		"dir/a.jpg", "dir/.git/config", "dir/node_modules/m/index.js", "ignore/b.jpg", "dir/cache/c.jpg",
+	})
+
+	for _, test := range []struct {
+		excludeDirs []string
+		want        []string
+	}{
+		{nil, []string{"root/dir/a.jpg", "root/dir/cache/c.jpg"}},
+		{[]string{"dir/cache"}, []string{
+			"root/dir/.git/config", "root/dir/a.jpg", "root/dir/node_modules/m/index.js", "root/ignore/b.jpg",
+		}},
+	} {
+		for _, workers := range []int{1, 4} {
+			report := &renfls.Report{}
+			opts := renfls.Options{Report: report, Workers: workers, Hidden: true}
+			condition := renfls.Condition{ExcludeDirs: test.excludeDirs}
+			if _, err := opts.Plan("root", ".", "new", condition); err != nil {
+				t.Errorf("Plan(%v) error: %s\n", test.excludeDirs, err)
+			}
+			var renamed []string
+			for _, entry := range report.Entries {
+				if entry.Status == renfls.StatusRenamed {
+					renamed = append(renamed, entry.Old)
+				}
+			}
+			if fmt.Sprint(renamed) != fmt.Sprint(test.want) {
+				t.Errorf("Plan(%v) with %d workers renamed %v, want %v\n",
+					test.excludeDirs, workers, renamed, test.want)
+			}
+		}
+	}
+
+	clearTestDir()
+}
+
+func TestExcludeSubDirs(t *testing.T) {
+	for _, rename := range []func() error{
+		func() error { return renfls.ToSubDirsName("root") },
+		func() error { return renfls.WalkToRootSubDirName("root", "root", renfls.Condition{}) },
+		func() error { return renfls.Options{Mode: renfls.ModeCopy}.ToSubDirsName("root") },
+	} {
+		createAlls("root", []string{".git/HEAD", ".git/config", "node_modules/x/index.js", "album/a.jpg"})
+
+		if err := rename(); err != nil {
+			t.Errorf("rename() error: %s\n", err)
+		}
+		for _, path := range []string{"root/.git/HEAD", "root/.git/config", "root/node_modules/x/index.js", "root/album.jpg"} {
+			if !isFileExist(path) {
+				t.Errorf("rename(): %s doesn't exist\n", path)
+			}
+		}
+
+		clearTestDir()
+	}
+}
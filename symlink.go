@@ -0,0 +1,125 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SymlinkPolicy is a way to walk symbolic links.
+type SymlinkPolicy int
+
+// Ways to walk symbolic links.
+const (
+	// SymlinkMove renames symbolic links themselves
+	// and doesn't walk directories that they refer to.
+	SymlinkMove SymlinkPolicy = iota
+	// SymlinkSkip leaves symbolic links as they are.
+	SymlinkSkip
+	// SymlinkTarget renames the files that symbolic links refer to
+	// instead of the links, and leaves links to directories as they are.
+	SymlinkTarget
+	// SymlinkFollow renames the files that symbolic links refer to
+	// and walks the directories that they refer to.
+	// A link to a directory that has the link is not walked.
+	SymlinkFollow
+)
+
+var symlinkPolicyNames = []string{"move", "skip", "target", "follow"}
+
+func (p SymlinkPolicy) String() string {
+	if p < 0 || int(p) >= len(symlinkPolicyNames) {
+		return fmt.Sprintf("SymlinkPolicy(%d)", int(p))
+	}
+	return symlinkPolicyNames[p]
+}
+
+// ParseSymlinkPolicy returns the policy of a name like "follow".
+func ParseSymlinkPolicy(name string) (SymlinkPolicy, error) {
+	for i, n := range symlinkPolicyNames {
+		if n == name {
+			return SymlinkPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("ParseSymlinkPolicy %q: unknown policy, want one of %s",
+		name, strings.Join(symlinkPolicyNames, ", "))
+}
+
+func isSymlink(info os.FileInfo) bool {
+	return info.Mode()&os.ModeSymlink != 0
+}
+
+// follow returns the info of the directory that a symbolic link refers to
+// if the options follow symbolic links, or info as it is.
+// loop is true if the directory is any of the ancestors of the link.
+func (r *renamer) follow(path string, info os.FileInfo, ancestors []os.FileInfo) (_ os.FileInfo, loop bool) {
+	if r.opts.Symlinks != SymlinkFollow || !isSymlink(info) {
+		return info, false
+	}
+	target, e := r.fs.Stat(path)
+	if e != nil || !target.IsDir() {
+		return info, false
+	}
+	for _, ancestor := range ancestors {
+		if os.SameFile(ancestor, target) {
+			return info, true
+		}
+	}
+	return target, false
+}
+
+// resolve returns the path and the info of the file that a symbolic link
+// refers to by the options, or the rule that ignores the link.
+func (r *renamer) resolve(path string, info os.FileInfo) (string, os.FileInfo, string) {
+	switch r.opts.Symlinks {
+	case SymlinkMove:
+		return path, info, ""
+	case SymlinkSkip:
+		return "", nil, "symlink"
+	}
+	target, e := filepath.EvalSymlinks(path)
+	if e != nil {
+		return "", nil, "broken symlink"
+	}
+	targetInfo, e := r.fs.Stat(target)
+	if e != nil {
+		return "", nil, "broken symlink"
+	}
+	if targetInfo.IsDir() {
+		return "", nil, "symlink to directory"
+	}
+	return target, targetInfo, ""
+}
+
+// fileSet is a set of files identified by their devices and inodes.
+type fileSet struct {
+	ids map[interface{}]bool
+	// infos has the files without ids, compared by os.SameFile.
+	infos []os.FileInfo
+}
+
+// add adds a file to the set and returns false if it is already in the set.
+func (s *fileSet) add(info os.FileInfo) bool {
+	id, ok := fileID(info)
+	if !ok {
+		for _, i := range s.infos {
+			if os.SameFile(i, info) {
+				return false
+			}
+		}
+		s.infos = append(s.infos, info)
+		return true
+	}
+	if s.ids == nil {
+		s.ids = make(map[interface{}]bool)
+	}
+	if s.ids[id] {
+		return false
+	}
+	s.ids[id] = true
+	return true
+}
@@ -0,0 +1,72 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestSymlinks(t *testing.T) {
+	for _, test := range []struct {
+		policy  renfls.SymlinkPolicy
+		workers int
+		renamed int
+		exist   []string
+		gone    []string
+	}{
+		{renfls.SymlinkMove, 1, 5,
+			[]string{"outside/b.txt", "outside/sub/c.txt"}, []string{"root/link.txt", "root/dirlink"}},
+		{renfls.SymlinkSkip, 1, 1,
+			[]string{"outside/b.txt", "root/link.txt", "root/dirlink", "root/real/loop"}, []string{"root/real/a.txt"}},
+		{renfls.SymlinkTarget, 1, 2,
+			[]string{"outside/sub/c.txt", "root/dirlink", "root/real/loop"}, []string{"outside/b.txt", "root/real/a.txt"}},
+		{renfls.SymlinkFollow, 1, 3,
+			[]string{"root/real/loop"}, []string{"outside/b.txt", "outside/sub/c.txt", "root/real/a.txt"}},
+		{renfls.SymlinkFollow, 4, 3,
+			[]string{"root/real/loop"}, []string{"outside/b.txt", "outside/sub/c.txt", "root/real/a.txt"}},
+	} {
+		createAlls(".", []string{"root/real/a.txt", "outside/b.txt", "outside/sub/c.txt"})
+		os.Mkdir("dest", os.ModePerm)
+		os.Symlink("../outside/b.txt", "root/link.txt")
+		os.Symlink("../outside/sub", "root/dirlink")
+		os.Symlink("real/a.txt", "root/dup.txt")
+		os.Symlink("..", "root/real/loop")
+
+		report := &renfls.Report{}
+		opts := renfls.Options{Symlinks: test.policy, Workers: test.workers, Report: report}
+		if err := opts.WalkRename("root", "dest", "new", renfls.Condition{}); err != nil {
+			t.Errorf("WalkRename(%s, %d workers) error: %s\n", test.policy, test.workers, err)
+		}
+		if report.Renamed != test.renamed {
+			t.Errorf("WalkRename(%s, %d workers): %d files renamed, want %d\n",
+				test.policy, test.workers, report.Renamed, test.renamed)
+		}
+		for _, path := range test.exist {
+			if _, err := os.Lstat(path); err != nil {
+				t.Errorf("WalkRename(%s, %d workers): %s doesn't exist\n", test.policy, test.workers, path)
+			}
+		}
+		for _, path := range test.gone {
+			if _, err := os.Lstat(path); err == nil {
+				t.Errorf("WalkRename(%s, %d workers): %s exists\n", test.policy, test.workers, path)
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestParseSymlinkPolicy(t *testing.T) {
+	for _, name := range []string{"move", "skip", "target", "follow"} {
+		policy, err := renfls.ParseSymlinkPolicy(name)
+		if err != nil || policy.String() != name {
+			t.Errorf("ParseSymlinkPolicy(%q) = %s, %v\n", name, policy, err)
+		}
+	}
+	if _, err := renfls.ParseSymlinkPolicy("loop"); err == nil {
+		t.Errorf("ParseSymlinkPolicy(%q) = nil error\n", "loop")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Template is a template of new file names.
+//
+// A template is a text with variables in braces:
+//
+//	{dir}     the name of the root directory
+//	{parent}  the name of the directory that has the file
+//	{name}    the file name without the extension
+//	{ext}     the extension without the leading dot
+//	{index}   the sequence number of the file in the root directory,
+//	          that can be padded with zeros like {index:03}
+//	{mtime}   the modification time formatted by a layout of package time
+//	          like {mtime:2006-01-02}, which is the default layout
+//	{size}    the file size in bytes
+//
+// "{{" and "}}" are a literal brace.
+// The extension of the file is always appended to the new name.
+type Template struct {
+	parts []templatePart
+}
+
+type templatePart struct {
+	literal string
+	// variable is the name of a variable if the part is not literal.
+	variable string
+	format   string
+}
+
+var templateVariables = map[string]bool{
+	"dir": true, "parent": true, "name": true, "ext": true,
+	"index": true, "mtime": true, "size": true,
+}
+
+// ParseTemplate parses a template of new file names.
+func ParseTemplate(text string) (*Template, error) {
+	parts, e := scanTemplate(text, parseTemplateVariable)
+	if e != nil {
+		return nil, fmt.Errorf("ParseTemplate %q: %s", text, e)
+	}
+	// New names are made in destination directories,
+	// so they can't have path separators.
+	for _, part := range parts {
+		if s := part.literal + part.format; strings.ContainsAny(s, "/"+string(filepath.Separator)) {
+			return nil, fmt.Errorf("ParseTemplate %q: path separator in a file name", text)
+		}
+	}
+	return &Template{parts}, nil
+}
+
+// scanTemplate splits a text into literals and variables in braces
+// parsed by variable.
+func scanTemplate(text string, variable func(s string) (templatePart, error)) ([]templatePart, error) {
+	var parts []templatePart
+	var literal []byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '}' {
+			if i+1 < len(text) && text[i+1] == '}' {
+				literal = append(literal, c)
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected \"}\"")
+		}
+		if c != '{' {
+			literal = append(literal, c)
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '{' {
+			literal = append(literal, c)
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(text[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed \"{\"")
+		}
+		part, e := variable(text[i+1 : i+end])
+		if e != nil {
+			return nil, e
+		}
+		if len(literal) > 0 {
+			parts = append(parts, templatePart{literal: string(literal)})
+			literal = nil
+		}
+		parts = append(parts, part)
+		i += end
+	}
+	if len(literal) > 0 {
+		parts = append(parts, templatePart{literal: string(literal)})
+	}
+	return parts, nil
+}
+
+func parseTemplateVariable(s string) (templatePart, error) {
+	name, format := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		name, format = s[:i], s[i+1:]
+	}
+	if !templateVariables[name] {
+		return templatePart{}, fmt.Errorf("unknown variable %q", name)
+	}
+
+	switch name {
+	case "index":
+		if format != "" {
+			if _, e := strconv.ParseUint(format, 10, 8); e != nil {
+				return templatePart{}, fmt.Errorf("invalid index format %q", format)
+			}
+		}
+		format = "%" + format + "d"
+	case "mtime":
+		if format == "" {
+			format = "2006-01-02"
+		}
+	default:
+		if format != "" {
+			return templatePart{}, fmt.Errorf("variable %q has no format", name)
+		}
+	}
+	return templatePart{variable: name, format: format}, nil
+}
+
+// templateData is the data of a file to execute a template.
+type templateData struct {
+	root  string
+	path  string
+	info  os.FileInfo
+	index int
+	// ext is the extension of the file with the leading dot.
+	ext string
+}
+
+func (t *Template) execute(data templateData) string {
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.variable == "" {
+			b.WriteString(part.literal)
+			continue
+		}
+		b.WriteString(data.value(part))
+	}
+	return b.String()
+}
+
+func (data templateData) value(part templatePart) string {
+	_, file := filepath.Split(data.path)
+	ext := data.ext
+	switch part.variable {
+	case "dir":
+		return filepath.Base(data.root)
+	case "parent":
+		return filepath.Base(filepath.Dir(data.path))
+	case "name":
+		return strings.TrimSuffix(file, ext)
+	case "ext":
+		return strings.TrimPrefix(ext, ".")
+	case "index":
+		return fmt.Sprintf(part.format, data.index)
+	case "mtime":
+		return data.info.ModTime().Format(part.format)
+	case "size":
+		return strconv.FormatInt(data.info.Size(), 10)
+	}
+	return ""
+}
+
+// RenameWith renames a file or a directory by a template
+// and moves it to a directory.
+func RenameWith(oldPath, dest, template string) (string, error) {
+	return Options{Name: template}.Rename(oldPath, dest, "")
+}
+
+// WalkRenameTemplate renames files that match a condition in a root directory
+// by a template and moves them to a destination directory.
+func WalkRenameTemplate(root, dest, template string, condition Condition) error {
+	return Options{Name: template}.WalkRename(root, dest, "", condition)
+}
@@ -0,0 +1,95 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestParseTemplate(t *testing.T) {
+	for _, test := range []struct {
+		template string
+		wantErr  bool
+	}{
+		{"{dir}_{mtime:2006-01-02}_{index:03}", false},
+		{"{parent}-{name}.{ext}-{size}", false},
+		{"{{literal}}", false},
+		{"{unknown}", true},
+		{"{dir", true},
+		{"dir}", true},
+		{"{index:abc}", true},
+		{"{name:03}", true},
+		{"{dir}/{name}", true},
+		{"{mtime:2006/01}", true},
+	} {
+		_, err := renfls.ParseTemplate(test.template)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseTemplate(%q) error = %v, want error %v\n",
+				test.template, err, test.wantErr)
+		}
+	}
+}
+
+func TestWalkRenameTemplate(t *testing.T) {
+	mtime := time.Date(2017, 5, 1, 12, 0, 0, 0, time.Local)
+
+	for _, test := range []struct {
+		mockFiles            []string
+		root, dest, template string
+		wantRenamedFileNames []string
+	}{
+		{
+			[]string{"a.jpg", "b.jpg", "sub/c.png"},
+			"trip", ".", "{dir}_{mtime:2006-01-02}_{index:03}",
+			[]string{"trip_2017-05-01_001.jpg", "trip_2017-05-01_002.jpg", "trip_2017-05-01_003.png"},
+		},
+		{
+			[]string{"a.jpg", "sub/c.png"},
+			"trip", ".", "{parent}-{name}-{ext}-{size}",
+			[]string{"trip-a-jpg-0.jpg", "sub-c-png-0.png"},
+		},
+		{
+			[]string{"a.jpg", "b.jpg"},
+			"trip", ".", "{{dir}}",
+			[]string{"{dir}.jpg", "{dir}-2.jpg"},
+		},
+	} {
+		createAlls(test.root, test.mockFiles)
+		for _, path := range test.mockFiles {
+			os.Chtimes(filepath.Join(test.root, path), mtime, mtime)
+		}
+
+		err := renfls.WalkRenameTemplate(test.root, test.dest, test.template, renfls.Condition{})
+		if err != nil {
+			t.Errorf("WalkRenameTemplate(%v) error: %s\n", test, err)
+		}
+
+		for _, fileName := range test.wantRenamedFileNames {
+			path := filepath.Join(test.dest, fileName)
+			if !isFileExist(path) {
+				t.Errorf("The new path %q didn't be created.\n", path)
+			}
+		}
+
+		clearTestDir()
+	}
+}
+
+func TestRenameWith(t *testing.T) {
+	createAll("dir/image.jpg")
+
+	newPath, err := renfls.RenameWith("dir/image.jpg", ".", "{parent}_{name}")
+	if err != nil {
+		t.Errorf("RenameWith() error: %s\n", err)
+	}
+	if want := "dir_image.jpg"; newPath != want {
+		t.Errorf("RenameWith() = %s, want %s\n", newPath, want)
+	}
+
+	clearTestDir()
+}
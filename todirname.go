@@ -8,8 +8,12 @@ import "path/filepath"
 // ToDirName renames all files in root
 // by the root directory name and moves these to a directory.
 func ToDirName(root, newDir string) error {
+	return newRenamer().toDirName(root, newDir)
+}
+
+func (r *renamer) toDirName(root, newDir string) error {
 	_, name := filepath.Split(root)
-	return WalkRenameAll(root, newDir, name)
+	return r.walkRename(root, newDir, name, nil)
 }
 
 // ToDirNamePattern renames all files matching pattern in root
@@ -36,6 +40,19 @@ func ToDirNameIgnoreExt(root, newDir string, exts []string) error {
 // WalkToRootDirName renames files that match a condition in a root directory
 // to the root directory name and moves them to a destination directory.
 func WalkToRootDirName(root, dest string, condition Condition) error {
+	return Options{}.WalkToRootDirName(root, dest, condition)
+}
+
+// WalkToRootDirName renames files that match a condition in a root directory
+// to the root directory name with the options
+// and moves them to a destination directory.
+func (opts Options) WalkToRootDirName(root, dest string, condition Condition) error {
+	return opts.run(func(r *renamer) error {
+		return r.walkToRootDirName(root, dest, condition)
+	})
+}
+
+func (r *renamer) walkToRootDirName(root, dest string, condition Condition) error {
 	_, name := filepath.Split(root)
-	return WalkRename(root, dest, name, condition)
+	return r.walkRenameCondition(root, dest, name, condition)
 }
@@ -25,19 +25,24 @@ func TestToDirNames(t *testing.T) {
 			"dir1.jpg",
 			"dir1.mp4",
 			"dir1.mp3",
-			"dir1.no",
 			"dir1",
 			"dir2.txt",
 			"dir2-2.txt",
 			"ディレクトリ3.txt",
 			"ディレクトリ3-2.txt",
 			"ディレクトリ3-3.txt",
+			// Hidden files are moved back to their directory,
+			// which has a suffix because of the renamed file "dir1".
+			"dir1-2/.no",
 		}},
 	} {
 		e := renfls.ToSubDirsName(test.root)
 		if e != nil {
 			t.Errorf("ToDirNames(%v) error: %s\n", test.root, e)
 		}
+		if isExist(filepath.Join(test.root, "fail")) {
+			t.Errorf("The temporary directory is left.\n")
+		}
 
 		for _, want := range test.wantFiles {
 			wantPath := filepath.Join(test.root, want)
@@ -109,7 +114,6 @@ func TestToDirNamesIgnoreExt(t *testing.T) {
 			"dir1.txt",
 			"dir2.txt",
 			"dir1.mp3",
-			"dir1.no",
 			"dir1",
 			"dir2-2.txt",
 			"ディレクトリ3.txt",
@@ -18,28 +18,125 @@ const (
 // ToSubDirsName renames all files in root
 // by the directories name in root and moves these to a directory.
 func ToSubDirsName(root string) error {
-	tempDir, e := moveDirs(root, tempDirName)
+	return Options{}.ToSubDirsName(root)
+}
+
+// ToSubDirsName renames all files in root by the directories name in root
+// with the options and moves these to a directory.
+func (opts Options) ToSubDirsName(root string) error {
+	return opts.run(func(r *renamer) error {
+		return r.toSubDirsName(root)
+	})
+}
+
+func (r *renamer) toSubDirsName(root string) error {
+	r.excludeDirs = DefaultExcludeDirs
+	if r.opts.Mode != ModeMove {
+		return r.eachSubDir(root, func(dir string) error {
+			return r.toDirName(dir, root)
+		})
+	}
+
+	tempDir, e := r.moveDirs(root, tempDirName)
+	if e != nil {
+		return e
+	}
+	if e := r.renameToDirName(tempDir, root); e != nil {
+		return e
+	}
+	if e := r.removeEmptyDirs(tempDir); e != nil {
+		return e
+	}
+	if r.isNotExist(tempDir) {
+		return nil
+	}
+	return r.restoreDirs(tempDir, root)
+}
+
+// restoreDirs moves the directories left in a temporary directory,
+// which have files like hidden files that are not renamed, back to a root.
+// A suffix is added to the name of a directory
+// if a renamed file in the root has the same name.
+func (r *renamer) restoreDirs(tempDir, root string) error {
+	infos, e := r.fs.ReadDir(tempDir)
 	if e != nil {
 		return e
 	}
-	if e := renameToDirName(tempDir, root); e != nil {
+
+	for _, info := range infos {
+		oldPath := filepath.Join(tempDir, info.Name())
+		path, e := r.addSuffixIfExist(root, info.Name(), "")
+		if e != nil {
+			return e
+		}
+		if e := r.fs.Rename(oldPath, path); e != nil {
+			e = &RenameError{"ToDirNames", oldPath, path, underlying(e)}
+			if e := r.fail(e); e != nil {
+				return e
+			}
+		}
+	}
+	return r.removeEmptyDirs(tempDir)
+}
+
+// removeEmptyDirs removes a directory if it has no files,
+// or the directories in it that have no files otherwise.
+// Files that are not renamed, like the files that failed, are left.
+func (r *renamer) removeEmptyDirs(dir string) error {
+	empty, e := r.isEmptyDir(dir)
+	if e != nil {
 		return e
 	}
-	if e := os.RemoveAll(tempDir); e != nil {
+	if empty {
+		return r.fs.RemoveAll(dir)
+	}
+
+	infos, e := r.fs.ReadDir(dir)
+	if e != nil {
 		return e
 	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		if e := r.removeEmptyDirs(filepath.Join(dir, info.Name())); e != nil {
+			return e
+		}
+	}
 	return nil
 }
 
-func renameToDirName(root, newDir string) error {
-	dirs, e := ioutil.ReadDir(root)
+// isEmptyDir returns whether a directory has no files in it
+// except directories.
+func (r *renamer) isEmptyDir(dir string) (bool, error) {
+	infos, e := r.fs.ReadDir(dir)
+	if e != nil {
+		return false, e
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			return false, nil
+		}
+		empty, e := r.isEmptyDir(filepath.Join(dir, info.Name()))
+		if e != nil || !empty {
+			return false, e
+		}
+	}
+	return true, nil
+}
+
+func (r *renamer) renameToDirName(root, newDir string) error {
+	dirs, e := r.fs.ReadDir(root)
 	if e != nil {
 		return e
 	}
 
 	for _, dir := range dirs {
 		path := filepath.Join(root, dir.Name())
-		if e := ToDirName(path, newDir); e != nil {
+		if r.skipSubDir(path) {
+			continue
+		}
+		if e := r.toDirName(path, newDir); e != nil {
 			return e
 		}
 	}
@@ -49,7 +146,7 @@ func renameToDirName(root, newDir string) error {
 // ToSubDirsNamePattern renames all files matching pattern in root
 // by the directories name in root and moves these to a directory.
 func ToSubDirsNamePattern(root, pattern string) error {
-	tempDir, e := moveDirs(root, ignoreDirName)
+	tempDir, e := newRenamer().moveDirs(root, ignoreDirName)
 	if e != nil {
 		return e
 	}
@@ -77,7 +174,7 @@ func renameToDirNamePattern(root, newDir, pattern string) error {
 // ToSubDirsNameExt renames all files matching extensions in root
 // by the directories name in root and moves these to a directory.
 func ToSubDirsNameExt(root string, exts []string) error {
-	tempDir, e := moveDirs(root, ignoreDirName)
+	tempDir, e := newRenamer().moveDirs(root, ignoreDirName)
 	if e != nil {
 		return e
 	}
@@ -105,7 +202,7 @@ func renameToDirNameExt(root, newDir string, exts []string) error {
 // ToSubDirsNameIgnoreExt renames all files not matching extensions in root
 // by the directories name in root and moves these to a directory.
 func ToSubDirsNameIgnoreExt(root string, exts []string) error {
-	tempDir, e := moveDirs(root, ignoreDirName)
+	tempDir, e := newRenamer().moveDirs(root, ignoreDirName)
 	if e != nil {
 		return e
 	}
@@ -118,26 +215,49 @@ func ToSubDirsNameIgnoreExt(root string, exts []string) error {
 // WalkToRootSubDirName renames files that match a condition in a root directory
 // to the sub directory name and moves them to a destination directory.
 func WalkToRootSubDirName(root, dest string, condition Condition) error {
-	tempDir, e := moveDirs(root, ignoreDirName)
+	return Options{}.WalkToRootSubDirName(root, dest, condition)
+}
+
+// WalkToRootSubDirName renames files that match a condition in a root directory
+// to the sub directory name with the options
+// and moves them to a destination directory.
+func (opts Options) WalkToRootSubDirName(root, dest string, condition Condition) error {
+	return opts.run(func(r *renamer) error {
+		return r.walkToRootSubDirName(root, dest, condition)
+	})
+}
+
+func (r *renamer) walkToRootSubDirName(root, dest string, condition Condition) error {
+	r.excludeDirs = condition.excludeDirs()
+	if r.opts.Mode != ModeMove {
+		return r.eachSubDir(root, func(dir string) error {
+			return r.walkToRootDirName(dir, dest, condition)
+		})
+	}
+
+	tempDir, e := r.moveDirs(root, ignoreDirName)
 	if e != nil {
 		return e
 	}
 
-	if e := walkToRootDirName(tempDir, dest, condition); e != nil {
+	if e := r.walkToSubDirsName(tempDir, dest, condition); e != nil {
 		return e
 	}
 	return nil
 }
 
-func walkToRootDirName(root, dest string, condition Condition) error {
-	dirs, e := ioutil.ReadDir(root)
+func (r *renamer) walkToSubDirsName(root, dest string, condition Condition) error {
+	dirs, e := r.fs.ReadDir(root)
 	if e != nil {
 		return e
 	}
 
 	for _, dir := range dirs {
 		path := filepath.Join(root, dir.Name())
-		if e := WalkToRootDirName(path, dest, condition); e != nil {
+		if r.skipSubDir(path) {
+			continue
+		}
+		if e := r.walkToRootDirName(path, dest, condition); e != nil {
 			return e
 		}
 	}
@@ -159,32 +279,71 @@ func renameToDirNameIgnoreExt(root, newDir string, exts []string) error {
 	return nil
 }
 
-func moveDirs(root, newDir string) (string, error) {
-	if isNotExist(root) {
-		return "", errorNotExist("ToDirNames", root)
+// eachSubDir calls fn for each directory in root
+// without moving the directories.
+func (r *renamer) eachSubDir(root string, fn func(dir string) error) error {
+	if _, e := r.fs.Stat(root); e != nil {
+		return errorNotExist("ToDirNames", root, "", ErrSourceNotFound, e)
+	}
+	infos, e := r.fs.ReadDir(root)
+	if e != nil {
+		return e
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() || r.skipSubDir(filepath.Join(root, info.Name())) {
+			continue
+		}
+		if e := fn(filepath.Join(root, info.Name())); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// skipSubDir returns whether a directory in a root is excluded
+// and records it.
+func (r *renamer) skipSubDir(path string) bool {
+	skip, rule := r.excludeDir(filepath.Base(path))
+	if skip {
+		r.record(ReportEntry{Old: path, Rule: rule, Status: StatusIgnored})
 	}
+	return skip
+}
 
-	dirs, e := ioutil.ReadDir(root)
+func (r *renamer) moveDirs(root, newDir string) (string, error) {
+	if _, e := r.fs.Stat(root); e != nil {
+		return "", errorNotExist("ToDirNames", root, "", ErrSourceNotFound, e)
+	}
+
+	dirs, e := r.fs.ReadDir(root)
 	if e != nil {
 		return "", e
 	}
 
 	tempDir := filepath.Join(root, newDir)
-	if isNotExist(tempDir) {
-		if e := os.Mkdir(tempDir, os.ModePerm); e != nil {
+	if r.isNotExist(tempDir) {
+		if e := r.fs.Mkdir(tempDir, os.ModePerm); e != nil {
 			return "", fmt.Errorf("ToDirNames: Temporary directory can't be created. %s", e)
 		}
 	}
 
 	for _, dir := range dirs {
-		if !dir.IsDir() {
+		if !dir.IsDir() || dir.Name() == newDir {
 			continue
 		}
 		path := filepath.Join(root, dir.Name())
-		dirInTempDir := filepath.Join(tempDir + "/" + dir.Name())
-		if e := os.Rename(path, dirInTempDir); e != nil {
+		// Excluded directories are left in the root.
+		if r.skipSubDir(path) {
 			continue
 		}
+		dirInTempDir := filepath.Join(tempDir + "/" + dir.Name())
+		if e := r.fs.Rename(path, dirInTempDir); e != nil {
+			e = &RenameError{"ToDirNames", path, dirInTempDir, underlying(e)}
+			if e := r.fail(e); e != nil {
+				return "", e
+			}
+		}
 	}
 
 	return tempDir, nil
@@ -0,0 +1,64 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import "fmt"
+
+// RollbackError is the error of a batch that was rolled back
+// because of a failure.
+type RollbackError struct {
+	// Err is the failure that caused the rollback.
+	Err error
+	// RolledBack is the operations reverted in reverse order.
+	RolledBack []JournalEntry
+	// Failures is the operations that can't be reverted.
+	Failures []UndoFailure
+}
+
+func (e *RollbackError) Error() string {
+	if len(e.Failures) == 0 {
+		return fmt.Sprintf("%s (rolled back %d operations)", e.Err, len(e.RolledBack))
+	}
+	return fmt.Sprintf("%s (rolled back %d operations, %d operations can't be reverted)%s",
+		e.Err, len(e.RolledBack), len(e.Failures), failuresString(e.Failures))
+}
+
+// Unwrap returns the failure that caused the rollback.
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// run runs a batch with a renamer of the options.
+// If the options are transactional and the batch fails,
+// every operation of the batch is rolled back.
+func (opts Options) run(batch func(r *renamer) error) error {
+	r, e := opts.renamer()
+	if e != nil {
+		return e
+	}
+	if !opts.Transactional {
+		return r.result(batch(r))
+	}
+
+	fs := r.fs
+	var entries []JournalEntry
+	r.fs = &journalFS{fs, func(entry JournalEntry) error {
+		entries = append(entries, entry)
+		return nil
+	}}
+
+	e = r.result(batch(r))
+	if e == nil {
+		// The backups of overwritten files are needed only to roll back
+		// unless they are journaled.
+		if opts.Journal == nil {
+			for _, backup := range r.backups {
+				fs.Remove(backup)
+			}
+		}
+		return nil
+	}
+	rolledBack, failures := undoAll(fs, entries)
+	return &RollbackError{Err: e, RolledBack: rolledBack, Failures: failures}
+}
@@ -0,0 +1,80 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestTransactional(t *testing.T) {
+	// The new name of the second file is too long to be renamed.
+	dir := strings.Repeat("d", 200)
+	mockFiles := []string{
+		filepath.Join(dir, "a.txt"),
+		filepath.Join(dir, "b."+strings.Repeat("x", 60)),
+		"dir/c.txt",
+	}
+	createAlls("root", mockFiles)
+
+	opts := renfls.Options{Transactional: true}
+	err := opts.WalkToRootSubDirName("root", "root", renfls.Condition{})
+
+	rollbackErr, ok := err.(*renfls.RollbackError)
+	if !ok {
+		t.Fatalf("WalkToRootSubDirName() = %v, want *RollbackError\n", err)
+	}
+	if len(rollbackErr.Failures) != 0 {
+		t.Errorf("Rollback failures = %v, want none\n", rollbackErr.Failures)
+	}
+	// ignore/, two moved directories and a renamed file.
+	if len(rollbackErr.RolledBack) != 4 {
+		t.Errorf("Rolled back %v, want 4 operations\n", rollbackErr.RolledBack)
+	}
+
+	for _, path := range mockFiles {
+		if !isFileExist(filepath.Join("root", path)) {
+			t.Errorf("The path %q didn't be rolled back.\n", path)
+		}
+	}
+	for _, path := range []string{"root/ignore", "root/" + dir + ".txt"} {
+		if isExist(path) {
+			t.Errorf("The path %q didn't be rolled back.\n", path)
+		}
+	}
+
+	clearTestDir()
+}
+
+func TestTransactionalOverwrite(t *testing.T) {
+	// The new name of the last file is too long to be renamed.
+	name := strings.Repeat("d", 200)
+	createAlls("root", []string{"a/x.txt", "b/x.txt", "c/x." + strings.Repeat("x", 60)})
+	ioutil.WriteFile("root/a/x.txt", []byte("a"), 0666)
+	ioutil.WriteFile("root/b/x.txt", []byte("b"), 0666)
+	defer clearTestDir()
+
+	// The overwritten file is restored by the rollback.
+	opts := renfls.Options{Transactional: true, Collision: renfls.CollisionPolicy{Strategy: renfls.CollisionOverwrite}}
+	if err := opts.WalkRename("root", "root", name, renfls.Condition{}); err == nil {
+		t.Fatalf("WalkRename() = nil, want *RollbackError\n")
+	}
+	for path, want := range map[string]string{"root/a/x.txt": "a", "root/b/x.txt": "b"} {
+		if b, _ := ioutil.ReadFile(path); string(b) != want {
+			t.Errorf("WalkRename(): %s has %q, want %q\n", path, b, want)
+		}
+	}
+
+	// The backup is removed if the batch succeeds.
+	if err := opts.WalkRename("root", "root", name, renfls.Condition{Exts: []string{"txt"}}); err != nil {
+		t.Errorf("WalkRename() error: %s\n", err)
+	}
+	newPath := "root/" + name + ".txt"
+	if b, _ := ioutil.ReadFile(newPath); string(b) != "b" || isExist(newPath+".renfls-bak") {
+		t.Errorf("WalkRename(): %s has %q with the backup %v\n", newPath, b, isExist(newPath+".renfls-bak"))
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright © 2017 shoarai
+
+// Package renfls provides interfaces to rename files in directory.
+package renfls
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// walk walks the file tree rooted at root on the file system of the renamer
+// in the same way as filepath.Walk.
+func (r *renamer) walk(root string, walkFn filepath.WalkFunc) error {
+	if r.opts.Workers > 1 {
+		return r.walkParallel(root, walkFn)
+	}
+
+	info, err := r.fs.Lstat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		info, _ = r.follow(root, info, nil)
+		err = r.walkDir(root, info, nil, walkFn)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walkDir walks a file under the ancestor directories.
+func (r *renamer) walkDir(path string, info os.FileInfo, ancestors []os.FileInfo, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+
+	infos, err := r.fs.ReadDir(path)
+	r.sortInfos(path, infos)
+	err1 := walkFn(path, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	ancestors = append(ancestors, info)
+	for _, fileInfo := range infos {
+		filename := filepath.Join(path, fileInfo.Name())
+		fileInfo, loop := r.follow(filename, fileInfo, ancestors)
+		if loop {
+			r.record(ReportEntry{Old: filename, Rule: "symlink loop", Status: StatusIgnored})
+			continue
+		}
+		if err := r.walkDir(filename, fileInfo, ancestors, walkFn); err != nil {
+			if !fileInfo.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DefaultExcludeDirs is the directories not walked
+// when Condition.ExcludeDirs is nil.
+// It has the temporary directories of ToSubDirsName and WalkToRootSubDirName.
+var DefaultExcludeDirs = []string{".git", "node_modules", "@eaDir", ignoreDirName, tempDirName}
+
+// skipDir returns whether a directory under a root is not walked
+// and the rule that skipped it.
+func (r *renamer) skipDir(root, path string) (bool, string) {
+	rel, e := filepath.Rel(root, path)
+	if e != nil || rel == "." {
+		return false, ""
+	}
+	rel = filepath.ToSlash(rel)
+	if skip, rule := r.excludeDir(rel); skip {
+		return true, rule
+	}
+	// Files in the directory are deeper than the directory by 1.
+	if r.opts.MaxDepth > 0 && depth(rel) >= r.opts.MaxDepth {
+		return true, fmt.Sprintf("deeper than max depth %d", r.opts.MaxDepth)
+	}
+	return false, ""
+}
+
+// excludeDir returns whether a directory of a slash-separated path
+// relative to a root is hidden or excluded, and the rule that excluded it.
+func (r *renamer) excludeDir(rel string) (bool, string) {
+	if !r.opts.Hidden && isHidden(path.Base(rel)) {
+		return true, "hidden"
+	}
+	for _, pattern := range r.excludeDirs {
+		if matchGlob(pattern, rel) {
+			return true, "exclude dir " + pattern
+		}
+	}
+	return false, ""
+}
+
+// depth returns the depth of a slash-separated path relative to a root.
+// Files directly in the root have depth 1.
+func depth(rel string) int {
+	if rel == "." {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
+
+// walkNode is a file in a file tree read in advance.
+type walkNode struct {
+	path     string
+	info     os.FileInfo
+	err      error
+	children []*walkNode
+	parent   *walkNode
+	// loop is true if the node is a symbolic link to an ancestor.
+	loop bool
+}
+
+// ancestors returns the infos of the node and its ancestors.
+func (node *walkNode) ancestors() []os.FileInfo {
+	var infos []os.FileInfo
+	for n := node; n != nil; n = n.parent {
+		infos = append(infos, n.info)
+	}
+	return infos
+}
+
+// walkParallel reads the file tree rooted at root with the workers
+// concurrently, and then walks it in the same order as walk.
+func (r *renamer) walkParallel(root string, walkFn filepath.WalkFunc) error {
+	info, err := r.fs.Lstat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		info, _ = r.follow(root, info, nil)
+		node := &walkNode{path: root, info: info}
+		r.readTree(node)
+		err = r.walkTree(node, walkFn)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// readTree reads the directories under a node with the workers.
+func (r *renamer) readTree(root *walkNode) {
+	if !root.info.IsDir() {
+		return
+	}
+
+	var mu sync.Mutex
+	cond := sync.NewCond(&mu)
+	queue := []*walkNode{root}
+	// pending is the number of directories queued or being read.
+	pending := 1
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				for len(queue) == 0 && pending > 0 {
+					cond.Wait()
+				}
+				if len(queue) == 0 {
+					mu.Unlock()
+					return
+				}
+				node := queue[len(queue)-1]
+				queue = queue[:len(queue)-1]
+				mu.Unlock()
+
+				infos, err := r.fs.ReadDir(node.path)
+				r.sortInfos(node.path, infos)
+				node.err = err
+				node.children = make([]*walkNode, len(infos))
+				var dirs []*walkNode
+				ancestors := node.ancestors()
+				for i, info := range infos {
+					child := &walkNode{path: filepath.Join(node.path, info.Name()), parent: node}
+					child.info, child.loop = r.follow(child.path, info, ancestors)
+					node.children[i] = child
+					if child.info.IsDir() {
+						if skip, _ := r.skipDir(root.path, child.path); !skip {
+							dirs = append(dirs, child)
+						}
+					}
+				}
+
+				mu.Lock()
+				queue = append(queue, dirs...)
+				pending += len(dirs) - 1
+				cond.Broadcast()
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *renamer) walkTree(node *walkNode, walkFn filepath.WalkFunc) error {
+	if node.loop {
+		r.record(ReportEntry{Old: node.path, Rule: "symlink loop", Status: StatusIgnored})
+		return nil
+	}
+	if !node.info.IsDir() {
+		return walkFn(node.path, node.info, nil)
+	}
+
+	err := walkFn(node.path, node.info, node.err)
+	if node.err != nil || err != nil {
+		return err
+	}
+
+	for _, child := range node.children {
+		if err := r.walkTree(child, walkFn); err != nil {
+			if !child.info.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
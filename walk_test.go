@@ -0,0 +1,50 @@
+// Copyright © 2017 shoarai
+
+package renfls_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/shoarai/renfls"
+)
+
+func TestWorkers(t *testing.T) {
+	var mockFiles []string
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			mockFiles = append(mockFiles, fmt.Sprintf("dir%d/sub%d/text%d.txt", i, j, j))
+		}
+		mockFiles = append(mockFiles, fmt.Sprintf("dir%d/image.jpg", i))
+	}
+	createAlls("root", mockFiles)
+
+	want, err := renfls.Plan("root", ".", "new", renfls.Condition{})
+	if err != nil {
+		t.Fatalf("Plan() error: %s\n", err)
+	}
+
+	opts := renfls.Options{Workers: 8}
+	operations, err := opts.Plan("root", ".", "new", renfls.Condition{})
+	if err != nil {
+		t.Errorf("Plan() with workers error: %s\n", err)
+	}
+	if !reflect.DeepEqual(operations, want) {
+		t.Errorf("Plan() with workers = %v, want %v\n", operations, want)
+	}
+
+	report := &renfls.Report{}
+	opts.Report = report
+	if err := opts.WalkRename("root", ".", "new", renfls.Condition{}); err != nil {
+		t.Errorf("WalkRename() with workers error: %s\n", err)
+	}
+	for i, entry := range report.Entries {
+		if entry.Old != want[i].Old || entry.New != want[i].New {
+			t.Errorf("WalkRename() with workers renamed %s to %s, want %s\n",
+				entry.Old, entry.New, want[i].New)
+		}
+	}
+
+	clearTestDir()
+}